@@ -2,41 +2,46 @@ package main
 
 import (
 	"context"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"execution-engine/internal/api"
+	"execution-engine/internal/buildcache"
 	"execution-engine/internal/engine"
 	"execution-engine/internal/executor"
+	"execution-engine/internal/language"
+	"execution-engine/internal/sandbox"
+	"execution-engine/internal/session"
 )
 
 func main() {
-	// ---- bootstrap docker executor ----
-	dockerExec, err := executor.NewDockerExecutor()
-	if err != nil {
-		panic(err)
-	}
+	// the local sandbox re-executes this binary as its init process
+	executor.MaybeRunSandboxInit()
 
-	// ---- preload docker images ----
-	// Scope the context for preloading
-	{
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
-		if err := dockerExec.PreloadImages(ctx); err != nil {
-			cancel()
-			log.Fatalf("❌ failed to preload images: %v", err)
+	// ---- languages beyond the built-ins ----
+	if f := os.Getenv("LANGUAGES_FILE"); f != "" {
+		if err := language.LoadFile(f); err != nil {
+			log.Fatalf("❌ failed to load languages: %v", err)
 		}
-		cancel()
+		log.Printf("Loaded languages from %s", f)
 	}
 
+	// ---- bootstrap sandbox runtime ----
+	rt := runtimeFromEnv()
+
 	// ---- engine ----
-	eng := engine.New(dockerExec)
+	eng := engine.New(rt, engine.WithRetention(retentionFromEnv()))
 
 	// ---- router ----
-	r := api.New(eng)
+	r := api.New(eng, api.WithTenantTokens(tenantTokensFromEnv()))
 
 	srv := &http.Server{
 		Addr:    ":8080",
@@ -76,5 +81,211 @@ func main() {
 		log.Fatal("Engine forced to shutdown: ", err)
 	}
 
+	// 3. Release anything the runtime keeps warm
+	if c, ok := rt.(io.Closer); ok {
+		if err := c.Close(); err != nil {
+			log.Printf("Runtime close: %v", err)
+		}
+	}
+
 	log.Println("Server exiting")
 }
+
+// runtimeFromEnv builds the sandbox backend selected by EXECUTOR_BACKEND:
+// "docker" (the default) or "local".
+func runtimeFromEnv() sandbox.Runtime {
+	switch backend := os.Getenv("EXECUTOR_BACKEND"); backend {
+	case "", "docker":
+		dockerExec, err := executor.NewDockerExecutor(
+			executor.WithOCIRuntimes(ociRuntimesFromEnv()),
+			executor.WithWarmPool(poolFromEnv()),
+			executor.WithBuildCache(buildCacheFromEnv()),
+			executor.WithImageSource(imageSourceFromEnv()),
+		)
+		if err != nil {
+			panic(err)
+		}
+
+		// ---- preload docker images ----
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+		defer cancel()
+		// languages whose image fails are served once a retry succeeds
+		preloadErr := dockerExec.PreloadImages(ctx)
+		if preloadErr != nil {
+			log.Printf("⚠️  starting without some languages: %v", preloadErr)
+		}
+		if err := dockerExec.ValidateRuntimes(ctx); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+
+		dockerExec.StartPool()
+		if preloadErr != nil {
+			dockerExec.StartImageRetry()
+		}
+		return dockerExec
+
+	case "local":
+		localExec, err := executor.NewLocalExecutor(os.Getenv("SANDBOX_CGROUP_ROOT"))
+		if err != nil {
+			log.Fatalf("❌ failed to set up local sandbox: %v", err)
+		}
+		log.Println("Using local namespace sandbox")
+		return localExec
+
+	default:
+		log.Fatalf("unknown EXECUTOR_BACKEND %q", backend)
+		return nil
+	}
+}
+
+// ociRuntimesFromEnv reads SANDBOX_OCI_RUNTIME (the default runtime) and
+// SANDBOX_OCI_RUNTIME_TENANTS, a comma separated list of tenant=runtime.
+func ociRuntimesFromEnv() executor.OCIRuntimes {
+	r := executor.OCIRuntimes{
+		Default: os.Getenv("SANDBOX_OCI_RUNTIME"),
+		Tenants: map[string]string{},
+	}
+
+	if v := os.Getenv("SANDBOX_OCI_RUNTIME_TENANTS"); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			tenant, rt, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || tenant == "" || rt == "" {
+				log.Fatalf("invalid SANDBOX_OCI_RUNTIME_TENANTS entry %q", pair)
+			}
+			r.Tenants[tenant] = rt
+		}
+	}
+
+	return r
+}
+
+// imageSourceFromEnv reads SANDBOX_IMAGE_ARCHIVE_DIR, a directory of
+// `docker save` tarballs to load images from, and SANDBOX_IMAGE_NO_PULL,
+// which forbids pulling images from a registry.
+func imageSourceFromEnv() executor.ImageSource {
+	src := executor.ImageSource{ArchiveDir: os.Getenv("SANDBOX_IMAGE_ARCHIVE_DIR")}
+
+	if v := os.Getenv("SANDBOX_IMAGE_NO_PULL"); v != "" {
+		noPull, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Fatalf("invalid SANDBOX_IMAGE_NO_PULL %q: %v", v, err)
+		}
+		src.NoPull = noPull
+	}
+
+	return src
+}
+
+// tenantTokensFromEnv reads API_TENANT_TOKENS, a comma separated list of
+// token=tenant. Requests carrying "Authorization: Bearer <token>" run for
+// that tenant.
+func tenantTokensFromEnv() map[string]string {
+	tokens := map[string]string{}
+
+	if v := os.Getenv("API_TENANT_TOKENS"); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			token, tenant, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok || token == "" || tenant == "" {
+				log.Fatalf("invalid API_TENANT_TOKENS entry")
+			}
+			tokens[token] = tenant
+		}
+	}
+
+	return tokens
+}
+
+// poolFromEnv sizes the docker warm pool from SANDBOX_POOL_MIN,
+// SANDBOX_POOL_MAX and SANDBOX_POOL_MAX_AGE. SANDBOX_POOL_MAX=0 disables it.
+func poolFromEnv() executor.PoolConfig {
+	cfg := executor.DefaultPoolConfig
+
+	if v := os.Getenv("SANDBOX_POOL_MIN"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid SANDBOX_POOL_MIN %q: %v", v, err)
+		}
+		cfg.Min = n
+	}
+	if v := os.Getenv("SANDBOX_POOL_MAX"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid SANDBOX_POOL_MAX %q: %v", v, err)
+		}
+		cfg.Max = n
+		if n == 0 {
+			cfg.Min = 0
+		}
+	}
+	if v := os.Getenv("SANDBOX_POOL_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid SANDBOX_POOL_MAX_AGE %q: %v", v, err)
+		}
+		cfg.MaxAge = d
+	}
+
+	// more idle containers than can ever run at once is waste
+	cfg.Max = min(cfg.Max, engine.MaxConcurrent)
+	cfg.Min = min(cfg.Min, cfg.Max)
+
+	return cfg
+}
+
+// buildCacheFromEnv opens the compile cache in SANDBOX_BUILD_CACHE_DIR,
+// bounded to SANDBOX_BUILD_CACHE_MAX_BYTES. A bound of 0 disables it.
+func buildCacheFromEnv() *buildcache.Cache {
+	maxBytes := int64(512 << 20)
+	if v := os.Getenv("SANDBOX_BUILD_CACHE_MAX_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 0 {
+			log.Fatalf("invalid SANDBOX_BUILD_CACHE_MAX_BYTES %q", v)
+		}
+		maxBytes = n
+	}
+	if maxBytes == 0 {
+		return nil
+	}
+
+	dir := os.Getenv("SANDBOX_BUILD_CACHE_DIR")
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "execution-engine-builds")
+	}
+
+	c, err := buildcache.Open(dir, maxBytes)
+	if err != nil {
+		log.Fatalf("❌ failed to open build cache: %v", err)
+	}
+	return c
+}
+
+// retentionFromEnv reads finished-session retention settings, falling back
+// to session.DefaultRetention for anything unset.
+func retentionFromEnv() session.Retention {
+	r := session.DefaultRetention
+
+	if v := os.Getenv("SESSION_RETENTION"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			log.Fatalf("invalid SESSION_RETENTION %q: %v", v, err)
+		}
+		r.TTL = d
+	}
+	if v := os.Getenv("SESSION_RETENTION_MAX"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid SESSION_RETENTION_MAX %q: %v", v, err)
+		}
+		r.MaxSessions = n
+	}
+	if v := os.Getenv("SESSION_RETENTION_MAX_BYTES"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			log.Fatalf("invalid SESSION_RETENTION_MAX_BYTES %q: %v", v, err)
+		}
+		r.MaxBytes = n
+	}
+	r.RemoveOnFetch = os.Getenv("SESSION_REMOVE_ON_FETCH") == "true"
+
+	return r
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"execution-engine/internal/engine"
+	"execution-engine/internal/session"
+)
+
+func RegisterArtifactsHTTP(r *gin.Engine, eng engine.Engine) {
+	r.GET("/session/:id/artifacts", func(c *gin.Context) {
+		sess, ok := eng.GetSession(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+
+		awaitArtifacts(c, sess)
+		kept, skipped := sess.Artifacts()
+		c.JSON(http.StatusOK, gin.H{
+			"sessionId": sess.ID,
+			"state":     sess.GetState(),
+			"artifacts": kept,
+			"skipped":   skipped,
+		})
+	})
+
+	r.GET("/session/:id/artifacts/*path", func(c *gin.Context) {
+		sess, ok := eng.GetSession(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+
+		awaitArtifacts(c, sess)
+		name := strings.TrimPrefix(c.Param("path"), "/")
+		a, ok := sess.Artifact(name)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "artifact not found"})
+			return
+		}
+
+		// never let a program's output render as a page on our origin
+		contentType := mime.TypeByExtension(path.Ext(a.Path))
+		if contentType == "" || strings.HasPrefix(contentType, "text/html") {
+			contentType = http.DetectContentType(a.Data)
+		}
+		if strings.HasPrefix(contentType, "text/html") {
+			contentType = "text/plain; charset=utf-8"
+		}
+		c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": path.Base(a.Path)}))
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Data(http.StatusOK, contentType, a.Data)
+	})
+}
+
+// awaitArtifacts waits for an ended session's workspace to be collected.
+// A killed session publishes its final state before the engine gets to
+// collect, so a client reacting to that state could otherwise see none.
+func awaitArtifacts(c *gin.Context, sess *session.Session) {
+	if !sess.GetState().IsTerminal() {
+		return
+	}
+	select {
+	case <-sess.CleanupDone():
+	case <-c.Request.Context().Done():
+	case <-time.After(stopTimeout):
+	}
+}
@@ -0,0 +1,116 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"execution-engine/internal/sandbox/fake"
+)
+
+func TestArtifacts(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{
+		Files: map[string]string{
+			"main.py":        "print(1)",
+			"out/result.csv": "a,b\n1,2\n",
+			"page.html":      "<script>alert(1)</script>",
+		},
+	})
+	id := createSession(t, srv, `{"language":"python","code":"","artifacts":["out/*.csv","*.html"]}`)
+	readUntilFinal(t, dial(t, srv, "/ws/session/"+id))
+
+	res, err := http.Get(srv.URL + "/session/" + id + "/artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list struct {
+		Artifacts []struct {
+			Path string `json:"path"`
+			Size int64  `json:"size"`
+		} `json:"artifacts"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&list)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Artifacts) != 2 || list.Artifacts[0].Path != "out/result.csv" || list.Artifacts[0].Size != 8 {
+		t.Errorf("artifacts = %+v", list.Artifacts)
+	}
+
+	get := func(path string) (*http.Response, string) {
+		t.Helper()
+		res, err := http.Get(srv.URL + "/session/" + id + "/artifacts/" + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		body, _ := io.ReadAll(res.Body)
+		return res, string(body)
+	}
+
+	res, body := get("out/result.csv")
+	if res.StatusCode != http.StatusOK || body != "a,b\n1,2\n" {
+		t.Errorf("result.csv: %d %q", res.StatusCode, body)
+	}
+
+	// program output must not be served as a page
+	res, _ = get("page.html")
+	if ct := res.Header.Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("page.html served as %q", ct)
+	}
+
+	if res, _ := get("main.py"); res.StatusCode != http.StatusNotFound {
+		t.Errorf("unrequested file: status %d", res.StatusCode)
+	}
+}
+
+func TestArtifactsBadPattern(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{})
+
+	res, err := http.Post(srv.URL+"/session", "application/json",
+		bytes.NewBufferString(`{"language":"python","code":"","artifacts":["../*"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("status %d, want 400", res.StatusCode)
+	}
+}
+
+// a killed session publishes its final state before the engine collects,
+// and the listing must still include what the program wrote
+func TestArtifactsAfterTimeLimit(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{
+		Files:     map[string]string{"partial.txt": "so far"},
+		Hang:      true,
+		KillDelay: 200 * time.Millisecond,
+	})
+	id := createSession(t, srv, `{"language":"python","code":"","timeLimitMs":50,"artifacts":["*.txt"]}`)
+	frames := readUntilFinal(t, dial(t, srv, "/ws/session/"+id))
+	if final := frames[len(frames)-1]; final.State != "TIMED_OUT" {
+		t.Fatalf("final state %q, want TIMED_OUT", final.State)
+	}
+
+	res, err := http.Get(srv.URL + "/session/" + id + "/artifacts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list struct {
+		Artifacts []struct {
+			Path string `json:"path"`
+		} `json:"artifacts"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&list)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Artifacts) != 1 || list.Artifacts[0].Path != "partial.txt" {
+		t.Errorf("artifacts = %+v", list.Artifacts)
+	}
+}
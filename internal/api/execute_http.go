@@ -0,0 +1,50 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"execution-engine/internal/artifact"
+	"execution-engine/internal/engine"
+	"execution-engine/internal/language"
+	"execution-engine/internal/modules"
+	"execution-engine/internal/project"
+)
+
+func RegisterExecuteHTTP(r *gin.Engine, eng engine.Engine) {
+	r.POST("/execute", func(c *gin.Context) {
+		var req modules.ExecuteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
+			return
+		}
+		req.Tenant = c.GetString(tenantKey)
+
+		res, err := eng.Execute(c.Request.Context(), req)
+		if err != nil {
+			if isRequestError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, language.ErrUnavailable) {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, res)
+	})
+}
+
+// isRequestError reports whether err from starting a session is the
+// client's fault.
+func isRequestError(err error) bool {
+	return errors.Is(err, language.ErrUnsupported) ||
+		errors.Is(err, project.ErrInvalid) ||
+		errors.Is(err, artifact.ErrInvalidPattern) ||
+		errors.Is(err, engine.ErrInputTooLarge)
+}
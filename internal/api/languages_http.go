@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"execution-engine/internal/language"
+)
+
+func RegisterLanguagesHTTP(r *gin.Engine) {
+	r.GET("/languages", func(c *gin.Context) {
+		languages := []gin.H{}
+		for _, spec := range language.AllSpecs() {
+			eff := spec.Effective()
+
+			limits := gin.H{"memoryBytes": eff.Memory}
+			if len(spec.CompileCmd) > 0 {
+				limits["compileMemoryBytes"] = eff.CompileMemory
+				limits["compileTimeoutMs"] = eff.CompileTimeout.Milliseconds()
+			}
+
+			lang := gin.H{
+				"id":       spec.ID(),
+				"name":     spec.Name,
+				"version":  spec.Version,
+				"default":  language.IsDefault(spec),
+				"image":    spec.Image,
+				"ready":    language.Ready(spec.ID()) == nil,
+				"fileName": spec.FileName,
+				"compiled": len(spec.CompileCmd) > 0,
+				"limits":   limits,
+			}
+			// known once the executor has resolved the image
+			st, ok := language.ImageStatusOf(spec.ID())
+			if ok {
+				lang["digest"] = st.Digest
+			}
+			lang["pinned"] = st.Pinned
+			languages = append(languages, lang)
+		}
+
+		c.JSON(http.StatusOK, gin.H{"languages": languages})
+	})
+}
@@ -0,0 +1,149 @@
+package api_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"execution-engine/internal/language"
+	"execution-engine/internal/sandbox/fake"
+)
+
+func TestLanguages(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{})
+
+	res, err := http.Get(srv.URL + "/languages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		Languages []struct {
+			ID       string `json:"id"`
+			Name     string `json:"name"`
+			Default  bool   `json:"default"`
+			Compiled bool   `json:"compiled"`
+			Limits   struct {
+				MemoryBytes      int64 `json:"memoryBytes"`
+				CompileTimeoutMs int64 `json:"compileTimeoutMs"`
+			} `json:"limits"`
+		} `json:"languages"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	defaults := map[string]string{}
+	byID := map[string]int{}
+	for i, l := range body.Languages {
+		byID[l.ID] = i
+		if l.Default {
+			if prev, ok := defaults[l.Name]; ok {
+				t.Errorf("%s has two defaults: %s and %s", l.Name, prev, l.ID)
+			}
+			defaults[l.Name] = l.ID
+		}
+	}
+	if defaults["python"] != "python@3.11" {
+		t.Errorf("python default = %q", defaults["python"])
+	}
+
+	i, ok := byID["cpp@c++20"]
+	if !ok {
+		t.Fatal("cpp@c++20 not listed")
+	}
+	if cpp := body.Languages[i]; !cpp.Compiled || cpp.Limits.CompileTimeoutMs == 0 || cpp.Limits.MemoryBytes == 0 {
+		t.Errorf("cpp@c++20 = %+v", cpp)
+	}
+}
+
+func TestLanguagesReportDigest(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{})
+
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	language.SetImageStatus("ruby@3.3", language.ImageStatus{ID: digest, Digest: digest, Pinned: true})
+
+	res, err := http.Get(srv.URL + "/languages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	var body struct {
+		Languages []struct {
+			ID     string `json:"id"`
+			Digest string `json:"digest"`
+			Pinned bool   `json:"pinned"`
+		} `json:"languages"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, l := range body.Languages {
+		switch {
+		case l.ID == "ruby@3.3" && l.Digest != digest:
+			t.Errorf("ruby@3.3 digest = %q, want %q", l.Digest, digest)
+		case l.ID == "ruby@3.3" && !l.Pinned:
+			t.Errorf("ruby@3.3 not pinned after its digest was verified")
+		case l.ID != "ruby@3.3" && l.Digest != "":
+			t.Errorf("%s digest = %q before its image was resolved", l.ID, l.Digest)
+		case l.ID != "ruby@3.3" && l.Pinned:
+			t.Errorf("%s pinned before its image was verified", l.ID)
+		}
+	}
+}
+
+func TestLanguageUnavailable(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{})
+
+	language.SetReady("ruby@3.3", false)
+	t.Cleanup(func() { language.SetReady("ruby@3.3", true) })
+
+	res, err := http.Post(srv.URL+"/session", "application/json",
+		bytes.NewBufferString(`{"language":"ruby","code":"puts 1"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var body struct {
+		Error string `json:"error"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&body)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusServiceUnavailable || !strings.Contains(body.Error, "language unavailable") {
+		t.Errorf("status %d %q, want 503 language unavailable", res.StatusCode, body.Error)
+	}
+
+	res, err = http.Get(srv.URL + "/languages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var list struct {
+		Languages []struct {
+			ID    string `json:"id"`
+			Ready bool   `json:"ready"`
+		} `json:"languages"`
+	}
+	err = json.NewDecoder(res.Body).Decode(&list)
+	res.Body.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, l := range list.Languages {
+		if l.Ready != (l.ID != "ruby@3.3") {
+			t.Errorf("%s ready = %v", l.ID, l.Ready)
+		}
+	}
+
+	// other languages keep working
+	createSession(t, srv, `{"language":"python","code":"print(1)"}`)
+
+	language.SetReady("ruby@3.3", true)
+	createSession(t, srv, `{"language":"ruby","code":"puts 1"}`)
+}
@@ -0,0 +1,21 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"execution-engine/internal/engine"
+	"execution-engine/internal/sandbox"
+)
+
+func RegisterPoolHTTP(r *gin.Engine, eng engine.Engine) {
+	r.GET("/pool", func(c *gin.Context) {
+		stats := eng.PoolStats()
+		if stats == nil {
+			stats = []sandbox.PoolStats{}
+		}
+
+		c.JSON(http.StatusOK, gin.H{"pools": stats})
+	})
+}
@@ -9,7 +9,12 @@ import (
 	"execution-engine/internal/engine"
 )
 
-func New(eng engine.Engine) *gin.Engine {
+func New(eng engine.Engine, opts ...Option) *gin.Engine {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	r := gin.Default()
 
 	r.Use(cors.New(cors.Config{
@@ -20,11 +25,17 @@ func New(eng engine.Engine) *gin.Engine {
 		MaxAge:           12 * time.Hour,
 	}))
 
+	r.Use(tenantAuth(o.tenantTokens))
+
 	// Serve the frontend
 	r.StaticFile("/", "./index.html")
 
 	RegisterSessionHTTP(r, eng)
 	RegisterSessionWS(r, eng)
+	RegisterExecuteHTTP(r, eng)
+	RegisterPoolHTTP(r, eng)
+	RegisterArtifactsHTTP(r, eng)
+	RegisterLanguagesHTTP(r)
 
 	return r
 }
@@ -1,13 +1,19 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
 	"execution-engine/internal/engine"
+	"execution-engine/internal/language"
 	"execution-engine/internal/modules"
+	"execution-engine/internal/session"
 )
 
 func RegisterSessionHTTP(r *gin.Engine, eng engine.Engine) {
@@ -17,9 +23,18 @@ func RegisterSessionHTTP(r *gin.Engine, eng engine.Engine) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid json"})
 			return
 		}
+		req.Tenant = c.GetString(tenantKey)
 
 		sess, err := eng.StartSession(c.Request.Context(), req)
 		if err != nil {
+			if isRequestError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, language.ErrUnavailable) {
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": err.Error()})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -30,4 +45,125 @@ func RegisterSessionHTTP(r *gin.Engine, eng engine.Engine) {
 			"sessionId": sess.ID,
 		})
 	})
+
+	r.GET("/session/:id/output", func(c *gin.Context) {
+		sess, ok := eng.GetSession(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+
+		chunks := []gin.H{}
+		for _, ev := range sess.Transcript() {
+			chunks = append(chunks, gin.H{
+				"stream": ev.Type,
+				"seq":    ev.Seq,
+				"time":   ev.Time,
+				"data":   ev.Data,
+			})
+		}
+
+		compileStdout, compileStderr := sess.GetCompileOutput()
+
+		state := sess.GetState()
+		c.JSON(http.StatusOK, gin.H{
+			"sessionId":     sess.ID,
+			"state":         state,
+			"combined":      sess.GetCombinedOutput(),
+			"chunks":        chunks,
+			"compileStdout": compileStdout,
+			"compileStderr": compileStderr,
+		})
+		if state.IsTerminal() {
+			eng.MarkFetched(sess.ID)
+		}
+	})
+
+	r.GET("/session/:id", func(c *gin.Context) {
+		sess, ok := eng.GetSession(c.Param("id"))
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+
+		c.JSON(http.StatusOK, sessionInfo(eng, sess))
+	})
+
+	r.DELETE("/session/:id", func(c *gin.Context) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), stopTimeout)
+		defer cancel()
+
+		sess, err := eng.StopSession(ctx, c.Param("id"))
+		if errors.Is(err, engine.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "session not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusGatewayTimeout, gin.H{"error": "timed out waiting for cleanup"})
+			return
+		}
+
+		log.Printf("Session %s stopped via API", sess.ID)
+		c.JSON(http.StatusOK, sessionInfo(eng, sess))
+	})
+
+	r.GET("/sessions", func(c *gin.Context) {
+		// ?state=RUNNING,WAITING (repeatable, case-insensitive)
+		want := map[session.State]bool{}
+		for _, v := range c.QueryArray("state") {
+			for _, st := range strings.Split(v, ",") {
+				if st = strings.TrimSpace(st); st != "" {
+					want[session.State(strings.ToUpper(st))] = true
+				}
+			}
+		}
+
+		list := []gin.H{}
+		for _, sess := range eng.ListSessions() {
+			if len(want) > 0 && !want[sess.GetState()] {
+				continue
+			}
+			list = append(list, sessionInfo(eng, sess))
+		}
+
+		c.JSON(http.StatusOK, gin.H{"sessions": list})
+	})
+}
+
+// stopTimeout bounds how long DELETE /session/:id waits for cleanup.
+const stopTimeout = 30 * time.Second
+
+func sessionInfo(eng engine.Engine, sess *session.Session) gin.H {
+	snap := sess.Snapshot()
+
+	info := gin.H{
+		"sessionId":   snap.ID,
+		"language":    snap.Language,
+		"state":       snap.State,
+		"tty":         snap.Tty,
+		"tenant":      snap.Tenant,
+		"timeLimitMs": snap.TimeLimit.Milliseconds(),
+		"createdAt":   snap.CreatedAt,
+		"startedAt":   timeOrNil(snap.StartedAt),
+		"finishedAt":  timeOrNil(snap.FinishedAt),
+		"activeWs":    snap.ActiveWS,
+	}
+
+	if snap.State == session.StateWaiting {
+		info["queuePosition"] = eng.QueuePosition(snap.ID)
+	}
+	if snap.State.IsTerminal() {
+		info["exitCode"] = snap.ReportedExitCode()
+		info["oomKilled"] = snap.OOMKilled
+		info["reason"] = snap.Reason
+	}
+
+	return info
+}
+
+func timeOrNil(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
 }
@@ -0,0 +1,154 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"execution-engine/internal/engine"
+	"execution-engine/internal/sandbox/fake"
+)
+
+type sessionInfo struct {
+	SessionID     string `json:"sessionId"`
+	State         string `json:"state"`
+	Reason        string `json:"reason"`
+	ExitCode      *int   `json:"exitCode"`
+	QueuePosition int    `json:"queuePosition"`
+	ActiveWS      int    `json:"activeWs"`
+}
+
+// request sends a body-less request and decodes the JSON response into out.
+func request(t *testing.T, srv *httptest.Server, method, path string, out any) int {
+	t.Helper()
+
+	req, err := http.NewRequest(method, srv.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer res.Body.Close()
+
+	if out != nil && res.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return res.StatusCode
+}
+
+// stopAll stops hanging sessions so the engine can shut down.
+func stopAll(t *testing.T, srv *httptest.Server, ids []string) {
+	t.Helper()
+	for _, id := range ids {
+		request(t, srv, http.MethodDelete, "/session/"+id, nil)
+	}
+}
+
+func TestSessionInfoAndStop(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{Hang: true})
+	id := createSession(t, srv, `{"language":"python","code":""}`)
+
+	var info sessionInfo
+	waitFor(t, func() bool {
+		request(t, srv, http.MethodGet, "/session/"+id, &info)
+		return info.State == "RUNNING"
+	})
+	if info.SessionID != id || info.ExitCode != nil {
+		t.Errorf("running session = %+v", info)
+	}
+
+	info = sessionInfo{}
+	if code := request(t, srv, http.MethodDelete, "/session/"+id, &info); code != http.StatusOK {
+		t.Fatalf("DELETE status = %d", code)
+	}
+	if info.State != "TERMINATED" || info.Reason != "cancelled" || info.ExitCode == nil || *info.ExitCode != -1 {
+		t.Errorf("stopped session = %+v", info)
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		if code := request(t, srv, method, "/session/nope", nil); code != http.StatusNotFound {
+			t.Errorf("%s unknown session: status %d, want 404", method, code)
+		}
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{Hang: true})
+
+	// one more than can run, so the last one queues
+	var ids []string
+	for range engine.MaxConcurrent + 1 {
+		ids = append(ids, createSession(t, srv, `{"language":"python","code":""}`))
+	}
+	defer stopAll(t, srv, ids)
+	queued := ids[len(ids)-1]
+
+	var list struct {
+		Sessions []sessionInfo `json:"sessions"`
+	}
+	waitFor(t, func() bool {
+		request(t, srv, http.MethodGet, "/sessions?state=running", &list)
+		return len(list.Sessions) == engine.MaxConcurrent
+	})
+
+	request(t, srv, http.MethodGet, "/sessions?state=WAITING", &list)
+	if len(list.Sessions) != 1 || list.Sessions[0].SessionID != queued || list.Sessions[0].QueuePosition != 1 {
+		t.Errorf("waiting sessions = %+v, want %s at position 1", list.Sessions, queued)
+	}
+
+	request(t, srv, http.MethodGet, "/sessions?state=running,waiting", &list)
+	if len(list.Sessions) != len(ids) {
+		t.Errorf("running or waiting: %d sessions, want %d", len(list.Sessions), len(ids))
+	}
+
+	request(t, srv, http.MethodGet, "/sessions?state=finished", &list)
+	if len(list.Sessions) != 0 {
+		t.Errorf("finished sessions = %+v, want none", list.Sessions)
+	}
+}
+
+func TestActiveWSCount(t *testing.T) {
+	activeWS := func(srv *httptest.Server, id string) int {
+		var info sessionInfo
+		request(t, srv, http.MethodGet, "/session/"+id, &info)
+		return info.ActiveWS
+	}
+
+	t.Run("stream ends", func(t *testing.T) {
+		srv, _ := newServer(t, fake.Script{Steps: []fake.Step{{Stdout: "hi\n"}}})
+		id := createSession(t, srv, `{"language":"python","code":""}`)
+
+		// the server ends the stream after the final state, without a close frame
+		readUntilFinal(t, dial(t, srv, "/ws/session/"+id))
+		waitFor(t, func() bool { return activeWS(srv, id) == 0 })
+	})
+
+	t.Run("client vanishes", func(t *testing.T) {
+		srv, _ := newServer(t, fake.Script{Hang: true})
+		id := createSession(t, srv, `{"language":"python","code":""}`)
+		defer stopAll(t, srv, []string{id})
+
+		conn := dial(t, srv, "/ws/session/"+id)
+		waitFor(t, func() bool { return activeWS(srv, id) == 1 })
+
+		conn.UnderlyingConn().Close()
+		waitFor(t, func() bool { return activeWS(srv, id) == 0 })
+	})
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
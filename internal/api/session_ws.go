@@ -1,16 +1,23 @@
 package api
 
 import (
+	"encoding/base64"
 	"log"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
 	"execution-engine/internal/engine"
+	"execution-engine/internal/session"
 )
 
+// wsWriteTimeout drops clients that stop reading, so they can't stall
+// the session's output forever.
+const wsWriteTimeout = 10 * time.Second
+
 var Upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool { return true },
 }
@@ -25,6 +32,17 @@ func RegisterSessionWS(r *gin.Engine, eng engine.Engine) {
 			return
 		}
 
+		// ?after=<seq> resumes right after the last event the client has seen
+		var after uint64
+		if v := c.Query("after"); v != "" {
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "invalid after"})
+				return
+			}
+			after = n
+		}
+
 		conn, err := Upgrader.Upgrade(c.Writer, c.Request, nil)
 		if err != nil {
 			return
@@ -33,84 +51,140 @@ func RegisterSessionWS(r *gin.Engine, eng engine.Engine) {
 
 		conn.SetCloseHandler(func(code int, text string) error {
 			log.Printf("WebSocket closed with code %d and text: %s", code, text)
-			sess.DetachWS()
-			log.Printf("WebSocket detached from session %s. Active connections: %d", sess.ID, sess.ActiveWSCount())
 			return nil
 		})
 
 		sess.AttachWS()
 		log.Printf("WS attached to %s (active=%d)", sess.ID, sess.ActiveWSCount())
+		// however the stream ends: final state, write error or a client
+		// that vanished without a close frame
+		defer func() {
+			sess.DetachWS()
+			log.Printf("WebSocket detached from session %s. Active connections: %d", sess.ID, sess.ActiveWSCount())
+		}()
+
+		// subscribe before reading so nothing slips between backlog and live events
+		backlog, sub := sess.Subscribe(after)
+		defer sub.Close()
 
 		// stdin
+		readerDone := make(chan struct{})
 		go func() {
+			defer close(readerDone)
 			for {
 				var msg struct {
-					Type string `json:"type"`
-					Data string `json:"data"`
+					Type   string `json:"type"`
+					Data   string `json:"data"`
+					Signal string `json:"signal"`
+					Cols   uint   `json:"cols"`
+					Rows   uint   `json:"rows"`
 				}
 
 				if err := conn.ReadJSON(&msg); err != nil {
 					return
 				}
 
-				if msg.Type == "input" {
+				switch msg.Type {
+				case "input":
 					_ = sess.WriteInput(msg.Data)
+				case "eof":
+					if err := sess.CloseInput(); err != nil {
+						log.Printf("Session %s: close stdin failed: %v", sess.ID, err)
+					}
+				case "signal":
+					if err := sess.Signal(msg.Signal); err != nil {
+						log.Printf("Session %s: signal failed: %v", sess.ID, err)
+					}
+				case "resize":
+					if err := sess.Resize(msg.Cols, msg.Rows); err != nil {
+						log.Printf("Session %s: resize failed: %v", sess.ID, err)
+					}
 				}
 			}
 		}()
 
-		lastStdout, lastStderr := 0, 0
-		lastState := sess.State
-
-		// Send initial state
-		conn.WriteJSON(gin.H{
-			"type":  "state",
-			"state": lastState,
-		})
-
-		ticker := time.NewTicker(40 * time.Millisecond)
-		defer ticker.Stop()
+		for _, ev := range backlog {
+			finished, err := sendEvent(conn, sess, ev)
+			if err != nil {
+				return
+			}
+			if finished {
+				eng.MarkFetched(sess.ID)
+				return
+			}
+		}
+		if sub.Ended {
+			// client resumed after the final state, nothing left to send
+			return
+		}
 
 		for {
 			select {
-			case <-sess.Done():
-				sendDiff(conn, "stdout", sess.GetStdout(), &lastStdout)
-				sendDiff(conn, "stderr", sess.GetStderr(), &lastStderr)
-
-				conn.WriteJSON(gin.H{
-					"type":  "state",
-					"state": sess.State,
-				})
-				log.Printf("Session %s finished", sess.ID)
-				return
-
-			case <-ticker.C:
-				if err := sendDiff(conn, "stdout", sess.GetStdout(), &lastStdout); err != nil {
+			case ev := <-sub.C:
+				finished, err := sendEvent(conn, sess, ev)
+				if err != nil {
 					return
 				}
-				if err := sendDiff(conn, "stderr", sess.GetStderr(), &lastStderr); err != nil {
+				if finished {
+					log.Printf("Session %s finished", sess.ID)
+					eng.MarkFetched(sess.ID)
 					return
 				}
 
-				// Check for state change
-				currentState := sess.State
-				if currentState != lastState {
-					conn.WriteJSON(gin.H{
-						"type":  "state",
-						"state": currentState,
-					})
-					lastState = currentState
-				}
+			case <-readerDone:
+				return
 			}
 		}
 	})
 }
 
-func sendDiff(conn *websocket.Conn, t string, data string, last *int) error {
-	if len(data) > *last {
-		chunk := data[*last:]
-		*last = len(data)
-		return conn.WriteJSON(gin.H{"type": t, "data": chunk})
+// sendEvent writes a session event to the client and reports whether it
+// was the final state of the session.
+func sendEvent(conn *websocket.Conn, sess *session.Session, ev session.Event) (bool, error) {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+
+	switch ev.Type {
+	case session.EventStdout, session.EventStderr,
+		session.EventCompileStdout, session.EventCompileStderr:
+		msg := gin.H{
+			"type": ev.Type,
+			"seq":  ev.Seq,
+			"time": ev.Time,
+			"data": ev.Data,
+		}
+		if sess.Tty && ev.Type == session.EventStdout {
+			// a terminal read can end mid character, which a JSON string
+			// would turn into U+FFFD; the raw bytes survive base64
+			msg["data"] = base64.StdEncoding.EncodeToString([]byte(ev.Data))
+			msg["encoding"] = "base64"
+		}
+		return false, conn.WriteJSON(msg)
+
+	case session.EventState:
+		if ev.State.IsTerminal() {
+			return true, conn.WriteJSON(finalStateFrame(sess, ev))
+		}
+		return false, conn.WriteJSON(gin.H{
+			"type":  "state",
+			"seq":   ev.Seq,
+			"time":  ev.Time,
+			"state": ev.State,
+		})
+	}
+	return false, nil
+}
+
+// finalStateFrame is the last state message of a session, carrying how
+// and why it ended.
+func finalStateFrame(sess *session.Session, ev session.Event) gin.H {
+	snap := sess.Snapshot()
+	return gin.H{
+		"type":      "state",
+		"seq":       ev.Seq,
+		"time":      ev.Time,
+		"state":     ev.State,
+		"exitCode":  snap.ReportedExitCode(),
+		"reason":    snap.Reason,
+		"oomKilled": snap.OOMKilled,
 	}
-	return nil
 }
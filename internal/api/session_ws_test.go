@@ -0,0 +1,302 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"execution-engine/internal/api"
+	"execution-engine/internal/engine"
+	"execution-engine/internal/sandbox/fake"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+type frame struct {
+	Type     string `json:"type"`
+	Seq      uint64 `json:"seq"`
+	Data     string `json:"data"`
+	Encoding string `json:"encoding"`
+	State    string `json:"state"`
+	ExitCode int    `json:"exitCode"`
+	Reason   string `json:"reason"`
+}
+
+func newServer(t *testing.T, script fake.Script) (*httptest.Server, *fake.Runtime) {
+	t.Helper()
+
+	rt := fake.New(script)
+	eng := engine.New(rt)
+	srv := httptest.NewServer(api.New(eng))
+	t.Cleanup(func() {
+		srv.Close()
+		eng.Shutdown(context.Background())
+	})
+	return srv, rt
+}
+
+func createSession(t *testing.T, srv *httptest.Server, body string) string {
+	t.Helper()
+
+	res, err := http.Post(srv.URL+"/session", "application/json", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatalf("POST /session: %v", err)
+	}
+	defer res.Body.Close()
+
+	var out struct {
+		SessionID string `json:"sessionId"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil || out.SessionID == "" {
+		t.Fatalf("bad /session response (status %d): %v", res.StatusCode, err)
+	}
+	return out.SessionID
+}
+
+func dial(t *testing.T, srv *httptest.Server, path string) *websocket.Conn {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + path
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial %s: %v", path, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUntilFinal collects frames until the final state frame arrives.
+func readUntilFinal(t *testing.T, conn *websocket.Conn) []frame {
+	t.Helper()
+
+	var frames []frame
+	for {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		var f frame
+		if err := conn.ReadJSON(&f); err != nil {
+			t.Fatalf("read: %v (frames so far: %+v)", err, frames)
+		}
+		frames = append(frames, f)
+		if f.Type == "state" && f.Reason != "" {
+			return frames
+		}
+	}
+}
+
+func stdout(frames []frame) string {
+	var b strings.Builder
+	for _, f := range frames {
+		if f.Type == "stdout" {
+			b.WriteString(f.Data)
+		}
+	}
+	return b.String()
+}
+
+func TestWSProtocol(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     fake.Script
+		send       []map[string]any
+		wantStdout string
+		wantState  string
+		wantReason string
+		wantCode   int
+	}{
+		{
+			name: "input is echoed",
+			script: fake.Script{
+				Steps: []fake.Step{{Stdout: "name? "}, {ReadLine: true, Echo: true}},
+			},
+			send:       []map[string]any{{"type": "input", "data": "bob\n"}},
+			wantStdout: "name? bob\n",
+			wantState:  "FINISHED",
+			wantReason: "exited",
+		},
+		{
+			name: "eof ends read-all",
+			script: fake.Script{
+				Steps: []fake.Step{{ReadAll: true, Echo: true}},
+			},
+			send: []map[string]any{
+				{"type": "input", "data": "1\n2\n"},
+				{"type": "eof"},
+			},
+			wantStdout: "1\n2\n",
+			wantState:  "FINISHED",
+			wantReason: "exited",
+		},
+		{
+			name:       "sigint",
+			script:     fake.Script{Hang: true},
+			send:       []map[string]any{{"type": "signal", "signal": "SIGINT"}},
+			wantState:  "FINISHED",
+			wantReason: "exited",
+			wantCode:   130,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv, _ := newServer(t, tt.script)
+			id := createSession(t, srv, `{"language":"python","code":""}`)
+			conn := dial(t, srv, "/ws/session/"+id)
+
+			// wait until the program runs before talking to it
+			for {
+				var f frame
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				if err := conn.ReadJSON(&f); err != nil {
+					t.Fatalf("read: %v", err)
+				}
+				if f.Type == "state" && f.State == "RUNNING" {
+					break
+				}
+			}
+			for _, msg := range tt.send {
+				if err := conn.WriteJSON(msg); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+			}
+
+			frames := readUntilFinal(t, conn)
+			final := frames[len(frames)-1]
+
+			if got := stdout(frames); got != tt.wantStdout {
+				t.Errorf("stdout = %q, want %q", got, tt.wantStdout)
+			}
+			if final.State != tt.wantState || final.Reason != tt.wantReason || final.ExitCode != tt.wantCode {
+				t.Errorf("final = %+v, want state=%s reason=%s code=%d",
+					final, tt.wantState, tt.wantReason, tt.wantCode)
+			}
+		})
+	}
+}
+
+// A killed program has no exit code of its own.
+func TestWSKilledExitCode(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{Hang: true})
+	id := createSession(t, srv, `{"language":"python","code":"","timeLimitMs":50}`)
+	conn := dial(t, srv, "/ws/session/"+id)
+
+	frames := readUntilFinal(t, conn)
+	if final := frames[len(frames)-1]; final.Reason != "time_limit" || final.ExitCode != -1 {
+		t.Errorf("final = %+v, want reason=time_limit code=-1", final)
+	}
+}
+
+func TestWSCompilePhase(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{
+		CompileStderr: "main.cpp:1: error\n",
+		CompileFailed: true,
+		ExitCode:      1,
+	})
+	id := createSession(t, srv, `{"language":"cpp","code":""}`)
+	conn := dial(t, srv, "/ws/session/"+id)
+
+	frames := readUntilFinal(t, conn)
+
+	var got []string
+	for _, f := range frames {
+		switch f.Type {
+		case "state":
+			got = append(got, f.State)
+		case "compile_stderr":
+			got = append(got, f.Data)
+		default:
+			t.Errorf("unexpected frame %+v", f)
+		}
+	}
+	want := []string{"WAITING", "COMPILING", "main.cpp:1: error\n", "FINISHED"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("frames = %q, want %q", got, want)
+	}
+	if final := frames[len(frames)-1]; final.Reason != "compile_error" || final.ExitCode != 1 {
+		t.Errorf("final = %+v, want compile_error with code 1", final)
+	}
+}
+
+func TestWSResume(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{
+		Steps: []fake.Step{{Stdout: "one\n"}, {Stderr: "two\n"}, {Stdout: "three\n"}},
+	})
+	id := createSession(t, srv, `{"language":"python","code":""}`)
+
+	first := readUntilFinal(t, dial(t, srv, "/ws/session/"+id))
+
+	// resume right after the first output chunk
+	var after uint64
+	for _, f := range first {
+		if f.Type == "stdout" {
+			after = f.Seq
+			break
+		}
+	}
+	if after == 0 {
+		t.Fatalf("no stdout frame in %+v", first)
+	}
+
+	resumed := readUntilFinal(t, dial(t, srv, "/ws/session/"+id+"?after="+strconv.FormatUint(after, 10)))
+
+	var got []string
+	for _, f := range resumed {
+		if f.Type != "state" {
+			got = append(got, f.Type+":"+f.Data)
+		}
+	}
+	want := "stderr:two\n,stdout:three\n"
+	if strings.Join(got, ",") != want {
+		t.Errorf("resumed output = %q, want %q", strings.Join(got, ","), want)
+	}
+}
+
+// A character split across two terminal reads must reach the client intact.
+func TestWSTTYBytes(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{
+		Steps: []fake.Step{{Stdout: "caf\xc3"}, {Delay: 10 * time.Millisecond, Stdout: "\xa9\n"}},
+	})
+	id := createSession(t, srv, `{"language":"python","code":"","tty":true}`)
+
+	var got []byte
+	for _, f := range readUntilFinal(t, dial(t, srv, "/ws/session/"+id)) {
+		if f.Type != "stdout" {
+			continue
+		}
+		if f.Encoding != "base64" {
+			t.Fatalf("tty frame encoding = %q, want base64", f.Encoding)
+		}
+		b, err := base64.StdEncoding.DecodeString(f.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, b...)
+	}
+	if string(got) != "café\n" {
+		t.Errorf("tty output = %q, want %q", got, "café\n")
+	}
+}
+
+func TestWSUnknownSession(t *testing.T) {
+	srv, _ := newServer(t, fake.Script{})
+
+	res, err := http.Get(srv.URL + "/ws/session/nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", res.StatusCode)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Option customizes the router built by New.
+type Option func(*options)
+
+type options struct {
+	tenantTokens map[string]string // bearer token -> tenant
+}
+
+// WithTenantTokens identifies tenants by the bearer token of a request.
+// Requests without a token run for no tenant; unknown tokens are refused.
+func WithTenantTokens(tokens map[string]string) Option {
+	return func(o *options) {
+		o.tenantTokens = tokens
+	}
+}
+
+const tenantKey = "tenant"
+
+// tenantAuth resolves the caller's tenant from its credentials, never from
+// the request body, since a tenant can select a stricter sandbox.
+func tenantAuth(tokens map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth := c.GetHeader("Authorization")
+		if auth == "" {
+			return
+		}
+
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		tenant, known := lookupToken(tokens, token)
+		if !ok || !known {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+		c.Set(tenantKey, tenant)
+	}
+}
+
+// lookupToken compares against every token in constant time.
+func lookupToken(tokens map[string]string, token string) (string, bool) {
+	var tenant string
+	var found bool
+	for t, name := range tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+			tenant, found = name, true
+		}
+	}
+	return tenant, found
+}
@@ -0,0 +1,67 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"execution-engine/internal/api"
+	"execution-engine/internal/engine"
+	"execution-engine/internal/sandbox/fake"
+)
+
+func TestTenantFromToken(t *testing.T) {
+	eng := engine.New(fake.New(fake.Script{}))
+	srv := httptest.NewServer(api.New(eng, api.WithTenantTokens(map[string]string{"s3cret": "acme"})))
+	t.Cleanup(func() {
+		srv.Close()
+		eng.Shutdown(context.Background())
+	})
+
+	start := func(token string) (int, string) {
+		t.Helper()
+		// the body's tenant must not count
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/session",
+			bytes.NewBufferString(`{"language":"python","code":"","tenant":"acme"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var out struct {
+			SessionID string `json:"sessionId"`
+		}
+		json.NewDecoder(res.Body).Decode(&out)
+		if out.SessionID == "" {
+			return res.StatusCode, ""
+		}
+
+		res, err = http.Get(srv.URL + "/session/" + out.SessionID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		var info struct {
+			Tenant string `json:"tenant"`
+		}
+		json.NewDecoder(res.Body).Decode(&info)
+		return http.StatusOK, info.Tenant
+	}
+
+	if status, tenant := start(""); status != http.StatusOK || tenant != "" {
+		t.Errorf("anonymous: status %d, tenant %q; want 200 and no tenant", status, tenant)
+	}
+	if status, tenant := start("s3cret"); status != http.StatusOK || tenant != "acme" {
+		t.Errorf("token: status %d, tenant %q; want 200 and acme", status, tenant)
+	}
+	if status, _ := start("guess"); status != http.StatusUnauthorized {
+		t.Errorf("unknown token: status %d, want 401", status)
+	}
+}
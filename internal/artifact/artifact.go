@@ -0,0 +1,190 @@
+// Package artifact collects the files a program leaves in its workspace, so
+// they can be fetched after the sandbox is gone.
+package artifact
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Limits bound what one session can keep.
+type Limits struct {
+	MaxFiles      int
+	MaxFileBytes  int64
+	MaxTotalBytes int64
+}
+
+var DefaultLimits = Limits{
+	MaxFiles:      32,
+	MaxFileBytes:  5 << 20,  // 5 MB
+	MaxTotalBytes: 10 << 20, // 10 MB
+}
+
+// MaxPatterns is the most globs one request may declare.
+const MaxPatterns = 16
+
+// ErrInvalidPattern is returned for globs that can't match inside the
+// workspace.
+var ErrInvalidPattern = errors.New("invalid artifact pattern")
+
+// Artifact is one collected file. Path is relative to the workspace.
+type Artifact struct {
+	Path string `json:"path"`
+	Size int64  `json:"size"`
+	Data []byte `json:"-"`
+}
+
+// Skipped is a matching file that was not kept, and why.
+type Skipped struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// Validate checks patterns before a session is queued. Patterns use
+// path.Match syntax on slash separated paths relative to the workspace,
+// plus ** for any number of directories.
+func Validate(patterns []string) error {
+	if len(patterns) > MaxPatterns {
+		return fmt.Errorf("%w: more than %d patterns", ErrInvalidPattern, MaxPatterns)
+	}
+	for _, p := range patterns {
+		if p == "" || path.IsAbs(p) || strings.Contains(p, "..") {
+			return fmt.Errorf("%w: %q", ErrInvalidPattern, p)
+		}
+		for _, seg := range strings.Split(p, "/") {
+			if _, err := path.Match(seg, ""); err != nil {
+				return fmt.Errorf("%w: %q", ErrInvalidPattern, p)
+			}
+		}
+	}
+	return nil
+}
+
+// Collect reads the regular files under dir matching any pattern, in path
+// order, within lim. Symlinks and other special files are never followed.
+func Collect(dir string, patterns []string, lim Limits) ([]Artifact, []Skipped, error) {
+	var (
+		kept    []Artifact
+		skipped []Skipped
+		total   int64
+	)
+
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == dir {
+				return err
+			}
+			// unreadable corners of the workspace just aren't collected
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if !matchAny(patterns, rel) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		switch {
+		case info.Size() > lim.MaxFileBytes:
+			skipped = append(skipped, Skipped{rel, "file too large"})
+			return nil
+		case len(kept) >= lim.MaxFiles:
+			skipped = append(skipped, Skipped{rel, "too many files"})
+			return nil
+		case total+info.Size() > lim.MaxTotalBytes:
+			skipped = append(skipped, Skipped{rel, "total size limit reached"})
+			return nil
+		}
+
+		data, err := readFile(p, lim.MaxFileBytes)
+		if err != nil {
+			skipped = append(skipped, Skipped{rel, err.Error()})
+			return nil
+		}
+		total += int64(len(data))
+		kept = append(kept, Artifact{Path: rel, Size: int64(len(data)), Data: data})
+		return nil
+	})
+	return kept, skipped, err
+}
+
+// readFile reads at most max bytes, in case the file grew since it was
+// listed. A killed program may still be running, so the file is checked
+// again once open: it may have been swapped for a symlink or a FIFO.
+func readFile(name string, max int64) ([]byte, error) {
+	f, err := os.OpenFile(name, os.O_RDONLY|noFollow, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, errors.New("not a regular file")
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > max {
+		return nil, errors.New("file too large")
+	}
+	return data, nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if match(strings.Split(p, "/"), strings.Split(name, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// match reports whether the path segments in name match the pattern
+// segments, with ** standing for zero or more segments. It fills in which
+// pattern suffix matches which name suffix once each, so a run of ** stays
+// cheap however deep the workspace is.
+func match(pattern, name []string) bool {
+	// ok[i][j] reports whether pattern[i:] matches name[j:]
+	ok := make([][]bool, len(pattern)+1)
+	for i := range ok {
+		ok[i] = make([]bool, len(name)+1)
+	}
+	ok[len(pattern)][len(name)] = true
+
+	for i := len(pattern) - 1; i >= 0; i-- {
+		for j := len(name); j >= 0; j-- {
+			if pattern[i] == "**" {
+				// no more segments, or one more
+				ok[i][j] = ok[i+1][j] || (j < len(name) && ok[i][j+1])
+				continue
+			}
+			if j < len(name) {
+				m, _ := path.Match(pattern[i], name[j])
+				ok[i][j] = m && ok[i+1][j+1]
+			}
+		}
+	}
+	return ok[0][0]
+}
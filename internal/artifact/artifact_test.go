@@ -0,0 +1,137 @@
+package artifact
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"out.csv", "out.csv", true},
+		{"*.png", "plot.png", true},
+		{"*.png", "img/plot.png", false},
+		{"img/*.png", "img/plot.png", true},
+		{"**/*.png", "plot.png", true},
+		{"**/*.png", "a/b/plot.png", true},
+		{"out/**", "out/a/b.txt", true},
+		{"out/**", "other/b.txt", false},
+		{"**", "anything/at/all", true},
+		{"**/**/x", "x", true},
+		{"a/**/b/**/c", "a/b/c", true},
+		{"a/**/b/**/c", "a/x/b/y/z/c", true},
+		{"a/**/b/**/c", "a/x/c", false},
+	}
+	for _, tt := range tests {
+		if got := matchAny([]string{tt.pattern}, tt.name); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, p := range []string{"", "/etc/*", "../*", "a/../../b", "[", "x/[a"} {
+		if err := Validate([]string{p}); !errors.Is(err, ErrInvalidPattern) {
+			t.Errorf("Validate(%q) = %v, want ErrInvalidPattern", p, err)
+		}
+	}
+	if err := Validate([]string{"*.csv", "out/**/*.png"}); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+// Many ** against a deep workspace must not take exponential time.
+func TestCollectDeepStars(t *testing.T) {
+	dir := t.TempDir()
+	deep := filepath.Join(dir, strings.Repeat("d/", 25))
+	if err := os.MkdirAll(deep, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(deep, "f"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	pattern := strings.Repeat("**/", 16) + "zz"
+	if err := Validate([]string{pattern}); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, _, err := Collect(dir, []string{pattern}, DefaultLimits); err != nil {
+			t.Errorf("Collect: %v", err)
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Collect did not finish")
+	}
+}
+
+func TestCollect(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) {
+		t.Helper()
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(strings.Repeat("x", size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("main.py", 10)
+	write("a.csv", 10)
+	write("out/b.csv", 10)
+	write("out/big.csv", 100)
+	write("out/c.csv", 10)
+
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secret, []byte("host file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(dir, "link.csv")); err != nil {
+		t.Fatal(err)
+	}
+
+	kept, skipped, err := Collect(dir, []string{"**/*.csv"}, Limits{
+		MaxFiles:      2,
+		MaxFileBytes:  50,
+		MaxTotalBytes: 1000,
+	})
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+
+	var got []string
+	for _, a := range kept {
+		got = append(got, a.Path)
+		if a.Size != int64(len(a.Data)) {
+			t.Errorf("%s: size %d, %d bytes", a.Path, a.Size, len(a.Data))
+		}
+	}
+	if strings.Join(got, " ") != "a.csv out/b.csv" {
+		t.Errorf("kept %v", got)
+	}
+
+	want := map[string]string{
+		"out/big.csv": "file too large",
+		"out/c.csv":   "too many files",
+	}
+	if len(skipped) != len(want) {
+		t.Errorf("skipped %v", skipped)
+	}
+	for _, s := range skipped {
+		if want[s.Path] != s.Reason {
+			t.Errorf("skipped %s because %q", s.Path, s.Reason)
+		}
+	}
+}
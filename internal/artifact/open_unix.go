@@ -0,0 +1,8 @@
+//go:build unix
+
+package artifact
+
+import "syscall"
+
+// noFollow makes opening a symlink fail, and a FIFO not wait for a writer.
+const noFollow = syscall.O_NOFOLLOW | syscall.O_NONBLOCK
@@ -0,0 +1,33 @@
+//go:build unix
+
+package artifact
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// Files listed by Collect can be swapped before they are read.
+func TestReadFileSwapped(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(secret, []byte("host file"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.csv")
+	if err := os.Symlink(secret, link); err != nil {
+		t.Fatal(err)
+	}
+	fifo := filepath.Join(dir, "fifo.csv")
+	if err := syscall.Mkfifo(fifo, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{link, fifo} {
+		if data, err := readFile(name, 100); err == nil {
+			t.Errorf("read %s: %q", filepath.Base(name), data)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Package buildcache is a content-addressed, size-bounded LRU cache of
+// compiled programs on local disk, so resubmitting identical code skips the
+// compiler.
+package buildcache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Key is everything that can change what the compiler produces.
+type Key struct {
+	Language   string
+	Image      string // digest of the image the compiler runs in
+	CompileCmd []string
+	Env        []string // sorted KEY=value pairs the compiler runs with
+	Source     string   // identifies the submitted files
+}
+
+// Hash is the key's content address.
+func (k Key) Hash() string {
+	h := sha256.New()
+	field := func(s string) {
+		// length prefixed so field boundaries can't be forged
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(s)))
+		h.Write(n[:])
+		h.Write([]byte(s))
+	}
+	field(k.Language)
+	field(k.Image)
+	field(fmt.Sprint(len(k.CompileCmd)))
+	for _, arg := range k.CompileCmd {
+		field(arg)
+	}
+	field(fmt.Sprint(len(k.Env)))
+	for _, kv := range k.Env {
+		field(kv)
+	}
+	field(k.Source)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Build is a cached compile: the files it left in the workspace and what
+// the compiler printed.
+type Build struct {
+	Dir    string
+	Stdout string
+	Stderr string
+}
+
+const (
+	buildDir   = "build"
+	stdoutFile = "compile_stdout"
+	stderrFile = "compile_stderr"
+	stagingPfx = ".staging-"
+)
+
+type entry struct {
+	hash     string
+	size     int64
+	lastUsed time.Time
+	pins     int
+}
+
+// Cache stores builds under a directory, evicting the least recently used
+// ones once they take more than maxBytes.
+type Cache struct {
+	root     string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*entry
+	size    int64
+}
+
+// Open loads the cache kept in root, creating it if needed.
+func Open(root string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &Cache{
+		root:     root,
+		maxBytes: maxBytes,
+		entries:  map[string]*entry{},
+	}
+
+	dirents, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range dirents {
+		path := filepath.Join(root, d.Name())
+		if strings.HasPrefix(d.Name(), stagingPfx) {
+			// left behind by a crash mid-store
+			os.RemoveAll(path)
+			continue
+		}
+		if !d.IsDir() {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		size, err := dirSize(path)
+		if err != nil {
+			continue
+		}
+		c.entries[d.Name()] = &entry{
+			hash:     d.Name(),
+			size:     size,
+			lastUsed: info.ModTime(),
+		}
+		c.size += size
+	}
+
+	c.mu.Lock()
+	c.evictLocked()
+	c.mu.Unlock()
+
+	return c, nil
+}
+
+// Load calls use with the build cached for key and reports whether there
+// was one. The build is not evicted while use runs; use must not modify it.
+func (c *Cache) Load(key Key, use func(Build) error) (bool, error) {
+	hash := key.Hash()
+
+	c.mu.Lock()
+	e, ok := c.entries[hash]
+	if !ok {
+		c.mu.Unlock()
+		return false, nil
+	}
+	e.pins++
+	e.lastUsed = time.Now()
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		e.pins--
+		c.evictLocked()
+		c.mu.Unlock()
+	}()
+
+	path := filepath.Join(c.root, hash)
+	// keep the LRU order across restarts
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+
+	stdout, _ := os.ReadFile(filepath.Join(path, stdoutFile))
+	stderr, _ := os.ReadFile(filepath.Join(path, stderrFile))
+
+	return true, use(Build{
+		Dir:    filepath.Join(path, buildDir),
+		Stdout: string(stdout),
+		Stderr: string(stderr),
+	})
+}
+
+// Store caches a build under key. fill writes the build's files into the
+// directory it is given. Storing a key that is already cached is a no-op.
+func (c *Cache) Store(key Key, stdout, stderr string, fill func(dir string) error) error {
+	hash := key.Hash()
+
+	c.mu.Lock()
+	_, exists := c.entries[hash]
+	c.mu.Unlock()
+	if exists {
+		return nil
+	}
+
+	staging, err := os.MkdirTemp(c.root, stagingPfx)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+
+	if err := os.Mkdir(filepath.Join(staging, buildDir), 0755); err != nil {
+		return err
+	}
+	if err := fill(filepath.Join(staging, buildDir)); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(staging, stdoutFile), []byte(stdout), 0644); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(staging, stderrFile), []byte(stderr), 0644); err != nil {
+		return err
+	}
+
+	size, err := dirSize(staging)
+	if err != nil {
+		return err
+	}
+	if size > c.maxBytes {
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.entries[hash]; exists {
+		// stored concurrently by an identical submission
+		return nil
+	}
+	if err := os.Rename(staging, filepath.Join(c.root, hash)); err != nil {
+		return err
+	}
+
+	c.entries[hash] = &entry{hash: hash, size: size, lastUsed: time.Now()}
+	c.size += size
+	c.evictLocked()
+	return nil
+}
+
+// must be called with c.mu held
+func (c *Cache) evictLocked() {
+	if c.size <= c.maxBytes {
+		return
+	}
+
+	lru := make([]*entry, 0, len(c.entries))
+	for _, e := range c.entries {
+		lru = append(lru, e)
+	}
+	sort.Slice(lru, func(i, j int) bool {
+		return lru[i].lastUsed.Before(lru[j].lastUsed)
+	})
+
+	for _, e := range lru {
+		if c.size <= c.maxBytes {
+			return
+		}
+		if e.pins > 0 {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(c.root, e.hash)); err != nil {
+			log.Printf("buildcache: evict %s: %v", e.hash, err)
+			continue
+		}
+		delete(c.entries, e.hash)
+		c.size -= e.size
+	}
+}
+
+func dirSize(root string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(root, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.Type().IsRegular() {
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,137 @@
+package buildcache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func key(src string) Key {
+	return Key{
+		Language:   "cpp",
+		Image:      "sha256:abc",
+		CompileCmd: []string{"g++", "main.cpp"},
+		Env:        []string{"CXXFLAGS=-O2"},
+		Source:     src,
+	}
+}
+
+func store(t *testing.T, c *Cache, k Key, size int) {
+	t.Helper()
+	err := c.Store(k, "", "warning\n", func(dir string) error {
+		return os.WriteFile(filepath.Join(dir, "a.out"), make([]byte, size), 0755)
+	})
+	if err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+}
+
+func cached(t *testing.T, c *Cache, k Key) bool {
+	t.Helper()
+	ok, err := c.Load(k, func(Build) error { return nil })
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	return ok
+}
+
+func TestKeyCoversEveryField(t *testing.T) {
+	base := key("int main(){}")
+	variants := []Key{
+		{Language: "c", Image: base.Image, CompileCmd: base.CompileCmd, Env: base.Env, Source: base.Source},
+		{Language: base.Language, Image: "sha256:def", CompileCmd: base.CompileCmd, Env: base.Env, Source: base.Source},
+		{Language: base.Language, Image: base.Image, CompileCmd: []string{"g++", "-O2", "main.cpp"}, Env: base.Env, Source: base.Source},
+		{Language: base.Language, Image: base.Image, CompileCmd: []string{"g++ main.cpp"}, Env: base.Env, Source: base.Source},
+		{Language: base.Language, Image: base.Image, CompileCmd: base.CompileCmd, Env: []string{"CXXFLAGS=-O0"}, Source: base.Source},
+		{Language: base.Language, Image: base.Image, CompileCmd: base.CompileCmd, Source: base.Source},
+		{Language: base.Language, Image: base.Image, CompileCmd: append(base.CompileCmd, base.Env...), Source: base.Source},
+		{Language: base.Language, Image: base.Image, CompileCmd: base.CompileCmd, Env: base.Env, Source: "int main(){return 1;}"},
+	}
+	for _, v := range variants {
+		if v.Hash() == base.Hash() {
+			t.Errorf("%+v hashes like %+v", v, base)
+		}
+	}
+	if key("int main(){}").Hash() != base.Hash() {
+		t.Error("hash is not deterministic")
+	}
+}
+
+func TestStoreLoad(t *testing.T) {
+	c, err := Open(t.TempDir(), 1<<20)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	if cached(t, c, key("a")) {
+		t.Fatal("hit on an empty cache")
+	}
+	store(t, c, key("a"), 10)
+
+	ok, err := c.Load(key("a"), func(b Build) error {
+		if b.Stderr != "warning\n" {
+			t.Errorf("stderr = %q", b.Stderr)
+		}
+		info, err := os.Stat(filepath.Join(b.Dir, "a.out"))
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm()&0100 == 0 {
+			t.Error("a.out lost its executable bit")
+		}
+		return nil
+	})
+	if !ok || err != nil {
+		t.Fatalf("Load = %v, %v", ok, err)
+	}
+}
+
+func TestEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	// room for two 100 byte builds plus their small compile logs
+	c, err := Open(dir, 250)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+
+	store(t, c, key("a"), 100)
+	store(t, c, key("b"), 100)
+	cached(t, c, key("a")) // b is now the oldest
+	store(t, c, key("c"), 100)
+
+	if !cached(t, c, key("a")) || cached(t, c, key("b")) || !cached(t, c, key("c")) {
+		t.Error("expected b to be evicted")
+	}
+
+	// the index survives a restart
+	c2, err := Open(dir, 250)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if !cached(t, c2, key("a")) || !cached(t, c2, key("c")) {
+		t.Error("entries lost on reopen")
+	}
+}
+
+func TestPinnedBuildIsNotEvicted(t *testing.T) {
+	c, err := Open(t.TempDir(), 150)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	store(t, c, key("a"), 100)
+
+	_, err = c.Load(key("a"), func(b Build) error {
+		// a newer build doesn't fit next to a, but a is in use
+		store(t, c, key("b"), 100)
+		_, err := os.Stat(filepath.Join(b.Dir, "a.out"))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("pinned build disappeared: %v", err)
+	}
+
+	// b was the only build that could go
+	if !cached(t, c, key("a")) || cached(t, c, key("b")) {
+		t.Error("expected a to stay and b to be evicted")
+	}
+}
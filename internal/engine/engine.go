@@ -4,11 +4,26 @@ import (
 	"context"
 
 	"execution-engine/internal/modules"
+	"execution-engine/internal/sandbox"
 	"execution-engine/internal/session"
 )
 
 type Engine interface {
+	Executor
+
 	StartSession(ctx context.Context, req modules.ExecuteRequest) (*session.Session, error)
 	GetSession(id string) (*session.Session, bool)
+	ListSessions() []*session.Session
+	// StopSession kills a session and waits until its resources are cleaned up.
+	StopSession(ctx context.Context, id string) (*session.Session, error)
+	// MarkFetched tells the engine a client received a session's final result.
+	MarkFetched(id string)
+	// QueuePosition is the 1-based position of a waiting session, 0 if it isn't queued.
+	QueuePosition(id string) int
+	// PoolStats reports the runtime's warm pools, nil if it has none.
+	PoolStats() []sandbox.PoolStats
+	// Execute runs a request to completion without an interactive client,
+	// feeding req.Inputs as stdin and closing it afterwards.
+	Execute(ctx context.Context, req modules.ExecuteRequest) (*modules.ExecuteResult, error)
 	Shutdown(ctx context.Context) error
 }
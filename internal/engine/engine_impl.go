@@ -2,46 +2,146 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"log"
 	"sync"
 	"time"
 
-	"execution-engine/internal/executor"
+	"execution-engine/internal/artifact"
+	"execution-engine/internal/language"
 	"execution-engine/internal/modules"
+	"execution-engine/internal/project"
+	"execution-engine/internal/sandbox"
 	"execution-engine/internal/session"
 )
 
+const (
+	// MaxTimeLimit is the server-side cap on a session's wall-clock run time.
+	// Requests without a TimeLimitMs get the full cap.
+	MaxTimeLimit = 2 * time.Minute
+
+	// MaxConcurrent is how many sessions may run at once; the rest queue.
+	MaxConcurrent = 10
+
+	// MaxInputBytes bounds the total size of a request's Inputs.
+	MaxInputBytes = 1 << 20
+)
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrInputTooLarge is returned for requests whose Inputs exceed
+	// MaxInputBytes.
+	ErrInputTooLarge = errors.New("inputs too large")
+)
+
 type engineImpl struct {
-	executor *executor.DockerExecutor
-	sessions *session.Manager
-	sem      chan struct{} // concurrency limiter
-	wg       sync.WaitGroup
+	runtime     sandbox.Runtime
+	sessions    *session.Manager
+	idleTimeout time.Duration
+	sem         chan struct{} // concurrency limiter
+	wg          sync.WaitGroup
+
+	queueMu sync.Mutex
+	queue   []queued // sessions waiting for a slot, oldest first
+}
+
+type queued struct {
+	id       string
+	language string
+}
+
+// Option customizes an engine built by New.
+type Option func(*engineImpl)
+
+// WithRetention sets how long finished sessions stay queryable.
+func WithRetention(r session.Retention) Option {
+	return func(e *engineImpl) {
+		e.sessions = session.NewManagerWithRetention(r)
+	}
+}
+
+// WithIdleTimeout sets how long an interactive session may go without
+// output or input before it is killed.
+func WithIdleTimeout(d time.Duration) Option {
+	return func(e *engineImpl) {
+		e.idleTimeout = d
+	}
 }
 
-func New(exec *executor.DockerExecutor) Engine {
-	return &engineImpl{
-		executor: exec,
-		sessions: session.NewManager(),
-		sem:      make(chan struct{}, 10), // 🔥 MAX 10 containers
+// New builds an engine that runs sessions on rt.
+func New(rt sandbox.Runtime, opts ...Option) Engine {
+	e := &engineImpl{
+		runtime:     rt,
+		sessions:    session.NewManager(),
+		idleTimeout: session.DefaultIdleTimeout,
+		sem:         make(chan struct{}, MaxConcurrent), // 🔥 MAX 10 containers
+	}
+	for _, opt := range opts {
+		opt(e)
 	}
+
+	// finished sessions stay around for late clients until the reaper drops them
+	e.sessions.StartReaper()
+
+	return e
 }
 
 func (e *engineImpl) StartSession(
 	ctx context.Context,
 	req modules.ExecuteRequest,
 ) (*session.Session, error) {
+	return e.startSession(req, false)
+}
+
+// startSession queues a session for execution. When closeStdin is set the
+// program sees EOF right after req.Inputs have been written.
+func (e *engineImpl) startSession(
+	req modules.ExecuteRequest,
+	closeStdin bool,
+) (*session.Session, error) {
+
+	spec, err := language.Resolve(req.Language)
+	if err != nil {
+		return nil, err
+	}
+	proj, err := project.FromRequest(req, spec.FileName)
+	if err != nil {
+		return nil, err
+	}
+	if err := artifact.Validate(req.Artifacts); err != nil {
+		return nil, err
+	}
+	if err := validateInputs(req.Inputs); err != nil {
+		return nil, err
+	}
+	if err := language.Ready(spec.ID()); err != nil {
+		return nil, err
+	}
 
 	// 1️⃣ Create LOGICAL session (WAITING)
 	sess := session.NewPending(
 		session.NewID(),
-		req.Language,
-		req.Code,
+		spec.ID(), // so "python" and "python@3.11" share a pool
+		proj,
 	)
 
+	sess.Tty = req.Tty
+	sess.Tenant = req.Tenant
+	sess.ArtifactPatterns = req.Artifacts
+	sess.SetTimeLimit(timeLimit(req.TimeLimitMs))
+	if closeStdin {
+		// nobody can send input, only the time limit applies
+		sess.SetIdleTimeout(0)
+	} else {
+		sess.SetIdleTimeout(e.idleTimeout)
+	}
+
 	e.sessions.Add(sess)
 
 	log.Printf("Engine: session %s created (WAITING)", sess.ID)
 
+	e.enqueue(sess.ID, sess.Language)
+
 	e.wg.Add(1)
 	// 2️⃣ Background goroutine tries to run it
 	go func() {
@@ -49,40 +149,75 @@ func (e *engineImpl) StartSession(
 		select {
 		case e.sem <- struct{}{}:
 			// slot acquired
+			e.dequeue(sess.ID)
 			log.Printf("Engine: slot acquired for session %s", sess.ID)
 
-			// start actual docker execution
-			err := e.executor.StartSession(
-				context.Background(),
+			// start actual sandboxed execution
+			proc, err := e.runtime.Start(
+				sess.Context(),
 				sess,
 			)
-			if err != nil {
+			var compileErr *sandbox.CompileError
+			switch {
+			case errors.As(err, &compileErr):
+				log.Printf("Engine: session %s did not compile: %v", sess.ID, err)
+				sess.MarkFinished(session.ExitStatus{
+					ExitCode:      compileErr.ExitCode,
+					OOMKilled:     compileErr.OOMKilled,
+					CompileFailed: true,
+				})
+			case err != nil:
 				log.Printf("Engine: failed to start session %s: %v", sess.ID, err)
-				sess.MarkTerminated()
+				sess.MarkTerminated(session.ReasonStartFailed)
+			}
+			if err != nil {
+				sess.SignalCleanup()
 				<-e.sem
-				e.sessions.Remove(sess.ID)
 				return
 			}
 
+			sess.SetRuntime(proc.ID(), proc.Stdin())
+			sess.SetController(proc)
 			sess.MarkRunning()
 
+			supervised := make(chan struct{})
+			go func() {
+				defer close(supervised)
+				e.supervise(sess, proc)
+			}()
+
+			if err := feedInputs(sess, req.Inputs); err != nil {
+				log.Printf("Engine: failed to write inputs for session %s: %v", sess.ID, err)
+			}
+			if closeStdin {
+				if err := sess.CloseInput(); err != nil {
+					log.Printf("Engine: failed to close stdin for session %s: %v", sess.ID, err)
+				}
+			}
+
 			// wait until execution finishes AND resources are cleaned up
-			<-sess.CleanupDone()
+			<-supervised
 
 			log.Printf(
 				"Engine: session %s finished (state=%s)",
 				sess.ID,
-				sess.State,
+				sess.GetState(),
 			)
 
-			e.sessions.Remove(sess.ID)
 			<-e.sem // 🔥 release slot
 
+		case <-sess.Done():
+			// stopped before it ever got a slot
+			e.dequeue(sess.ID)
+			log.Printf("Engine: session %s cancelled while waiting", sess.ID)
+			sess.SignalCleanup()
+
 		case <-time.After(2 * time.Minute):
 			// optional: waiting timeout
+			e.dequeue(sess.ID)
 			log.Printf("Engine: session %s timed out while waiting", sess.ID)
-			sess.MarkTerminated()
-			e.sessions.Remove(sess.ID)
+			sess.MarkTerminated(session.ReasonCancelled)
+			sess.SignalCleanup()
 		}
 	}()
 
@@ -93,9 +228,90 @@ func (e *engineImpl) GetSession(id string) (*session.Session, bool) {
 	return e.sessions.Get(id)
 }
 
+func (e *engineImpl) ListSessions() []*session.Session {
+	return e.sessions.List()
+}
+
+func (e *engineImpl) StopSession(ctx context.Context, id string) (*session.Session, error) {
+	sess, ok := e.sessions.Get(id)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+
+	sess.Stop()
+
+	select {
+	case <-sess.CleanupDone():
+		return sess, nil
+	case <-ctx.Done():
+		return sess, ctx.Err()
+	}
+}
+
+func (e *engineImpl) MarkFetched(id string) {
+	e.sessions.MarkFetched(id)
+}
+
+func (e *engineImpl) QueuePosition(id string) int {
+	e.queueMu.Lock()
+	defer e.queueMu.Unlock()
+
+	for i, q := range e.queue {
+		if q.id == id {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (e *engineImpl) PoolStats() []sandbox.PoolStats {
+	if w, ok := e.runtime.(sandbox.Warmer); ok {
+		return w.PoolStats()
+	}
+	return nil
+}
+
+func (e *engineImpl) enqueue(id, lang string) {
+	e.queueMu.Lock()
+	defer e.queueMu.Unlock()
+
+	e.queue = append(e.queue, queued{id: id, language: lang})
+	e.want(lang)
+}
+
+func (e *engineImpl) dequeue(id string) {
+	e.queueMu.Lock()
+	defer e.queueMu.Unlock()
+
+	for i, q := range e.queue {
+		if q.id == id {
+			e.queue = append(e.queue[:i], e.queue[i+1:]...)
+			e.want(q.language)
+			return
+		}
+	}
+}
+
+// want tells a warming runtime how many sessions of lang are queued.
+// Callers hold queueMu.
+func (e *engineImpl) want(lang string) {
+	w, ok := e.runtime.(sandbox.Warmer)
+	if !ok {
+		return
+	}
+
+	waiting := 0
+	for _, q := range e.queue {
+		if q.language == lang {
+			waiting++
+		}
+	}
+	w.Want(lang, waiting)
+}
+
 func (e *engineImpl) Shutdown(ctx context.Context) error {
 	log.Println("Engine: shutting down, waiting for active sessions...")
-	
+
 	done := make(chan struct{})
 	go func() {
 		e.wg.Wait()
@@ -105,8 +321,19 @@ func (e *engineImpl) Shutdown(ctx context.Context) error {
 	select {
 	case <-done:
 		log.Println("Engine: all sessions finished.")
+		e.sessions.StopReaper()
 		return nil
 	case <-ctx.Done():
 		return ctx.Err()
 	}
 }
+
+// timeLimit converts a requested limit into the enforced one,
+// clamped to MaxTimeLimit.
+func timeLimit(ms int64) time.Duration {
+	d := time.Duration(ms) * time.Millisecond
+	if d <= 0 || d > MaxTimeLimit {
+		return MaxTimeLimit
+	}
+	return d
+}
@@ -0,0 +1,531 @@
+package engine_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"execution-engine/internal/engine"
+	"execution-engine/internal/modules"
+	"execution-engine/internal/project"
+	"execution-engine/internal/sandbox"
+	"execution-engine/internal/sandbox/fake"
+	"execution-engine/internal/session"
+)
+
+func TestExecute(t *testing.T) {
+	tests := []struct {
+		name   string
+		script fake.Script
+		req    modules.ExecuteRequest
+		want   modules.ExecuteResult
+	}{
+		{
+			name: "stdout and exit code",
+			script: fake.Script{
+				Steps:    []fake.Step{{Stdout: "hello\n"}},
+				ExitCode: 3,
+			},
+			want: modules.ExecuteResult{
+				ExitCode: 3,
+				Stdout:   "hello\n",
+				Output:   "hello\n",
+				Reason:   "exited",
+			},
+		},
+		{
+			name: "interleaved output",
+			script: fake.Script{
+				Steps: []fake.Step{
+					{Stdout: "a\n"},
+					{Stderr: "boom\n"},
+					{Stdout: "b\n"},
+				},
+			},
+			want: modules.ExecuteResult{
+				Stdout: "a\nb\n",
+				Stderr: "boom\n",
+				Output: "a\nboom\nb\n",
+				Reason: "exited",
+			},
+		},
+		{
+			name: "inputs fed as lines then EOF",
+			script: fake.Script{
+				Steps: []fake.Step{{ReadAll: true, Echo: true}},
+			},
+			req: modules.ExecuteRequest{Inputs: []string{"1 2", "3\n"}},
+			want: modules.ExecuteResult{
+				Stdout: "1 2\n3\n",
+				Output: "1 2\n3\n",
+				Reason: "exited",
+			},
+		},
+		{
+			name:   "oom",
+			script: fake.Script{ExitCode: 137, OOMKilled: true},
+			want: modules.ExecuteResult{
+				ExitCode:  137,
+				OOMKilled: true,
+				Reason:    "oom",
+			},
+		},
+		{
+			name:   "compile error",
+			script: fake.Script{CompileStderr: "error: x\n", ExitCode: 1, CompileFailed: true},
+			want: modules.ExecuteResult{
+				ExitCode:      1,
+				Reason:        "compile_error",
+				CompileStderr: "error: x\n",
+			},
+		},
+		{
+			name: "compiled",
+			script: fake.Script{
+				CompileStderr: "warning: y\n",
+				Steps:         []fake.Step{{Stdout: "ok\n"}},
+			},
+			want: modules.ExecuteResult{
+				Stdout:        "ok\n",
+				Output:        "ok\n",
+				Reason:        "exited",
+				CompileStderr: "warning: y\n",
+			},
+		},
+		{
+			name:   "time limit",
+			script: fake.Script{Hang: true},
+			req:    modules.ExecuteRequest{TimeLimitMs: 50},
+			want: modules.ExecuteResult{
+				ExitCode: -1,
+				TimedOut: true,
+				Reason:   "time_limit",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			eng := engine.New(fake.New(tt.script))
+			defer eng.Shutdown(context.Background())
+
+			req := tt.req
+			req.Language = "python"
+
+			got, err := eng.Execute(context.Background(), req)
+			if err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			got.DurationMs = 0
+			got.SessionID = ""
+			if *got != tt.want {
+				t.Errorf("got %+v\nwant %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+// A program that never reads its stdin must not keep the time limit from
+// killing it.
+func TestTimeLimitUnreadInput(t *testing.T) {
+	eng := engine.New(fake.New(fake.Script{Hang: true}))
+	defer eng.Shutdown(context.Background())
+
+	type result struct {
+		res *modules.ExecuteResult
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		res, err := eng.Execute(context.Background(), modules.ExecuteRequest{
+			Language:    "python",
+			TimeLimitMs: 200,
+			Inputs:      []string{"hi"},
+		})
+		done <- result{res, err}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("Execute: %v", r.err)
+		}
+		if !r.res.TimedOut || r.res.Reason != "time_limit" {
+			t.Errorf("got %+v, want a time limit kill", *r.res)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("time limit did not kill a program blocking on input")
+	}
+}
+
+// An interactive session that goes quiet is killed long before the
+// default time limit.
+func TestIdleTimeout(t *testing.T) {
+	eng := engine.New(
+		fake.New(fake.Script{Steps: []fake.Step{{ReadLine: true}}}),
+		engine.WithIdleTimeout(20*time.Millisecond),
+	)
+	defer eng.Shutdown(context.Background())
+
+	sess, err := eng.StartSession(context.Background(), modules.ExecuteRequest{Language: "python"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	waitDone(t, sess)
+
+	snap := sess.Snapshot()
+	if snap.State != session.StateTerminated || snap.Reason != session.ReasonIdleTimeout {
+		t.Errorf("got state=%s reason=%s, want TERMINATED idle_timeout", snap.State, snap.Reason)
+	}
+	if snap.TimeLimit != engine.MaxTimeLimit {
+		t.Errorf("time limit = %s, want the default %s", snap.TimeLimit, engine.MaxTimeLimit)
+	}
+}
+
+// A batch program nobody can send input to runs until its time limit,
+// however quiet it is.
+func TestExecuteIgnoresIdleTimeout(t *testing.T) {
+	eng := engine.New(
+		fake.New(fake.Script{Steps: []fake.Step{{Stderr: "working\n"}}, Hang: true}),
+		engine.WithIdleTimeout(10*time.Millisecond),
+	)
+	defer eng.Shutdown(context.Background())
+
+	res, err := eng.Execute(context.Background(), modules.ExecuteRequest{
+		Language:    "python",
+		TimeLimitMs: 100,
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if res.Reason != string(session.ReasonTimeLimit) || !res.TimedOut {
+		t.Errorf("got reason=%s timedOut=%v, want time_limit", res.Reason, res.TimedOut)
+	}
+}
+
+// With remove-on-fetch, an /execute result that declared artifacts still
+// names a session they can be fetched from.
+func TestExecuteRemoveOnFetch(t *testing.T) {
+	r := session.DefaultRetention
+	r.RemoveOnFetch = true
+	eng := engine.New(
+		fake.New(fake.Script{Files: map[string]string{"out.txt": "hi"}}),
+		engine.WithRetention(r),
+	)
+	defer eng.Shutdown(context.Background())
+
+	plain, err := eng.Execute(context.Background(), modules.ExecuteRequest{Language: "python"})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	withArtifacts, err := eng.Execute(context.Background(), modules.ExecuteRequest{
+		Language:  "python",
+		Artifacts: []string{"*.txt"},
+	})
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		_, ok := eng.GetSession(plain.SessionID)
+		return !ok
+	})
+	sess, ok := eng.GetSession(withArtifacts.SessionID)
+	if !ok {
+		t.Fatal("session with artifacts was removed")
+	}
+	<-sess.CleanupDone()
+	if _, ok := sess.Artifact("out.txt"); !ok {
+		t.Error("artifact out.txt not collected")
+	}
+}
+
+func TestExecuteCancelled(t *testing.T) {
+	eng := engine.New(fake.New(fake.Script{Hang: true}))
+	defer eng.Shutdown(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := eng.Execute(ctx, modules.ExecuteRequest{
+		Language: "python",
+		Inputs:   []string{"never read"},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want deadline exceeded", err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("Execute returned %s after its context ended", d)
+	}
+}
+
+func TestExecuteInputTooLarge(t *testing.T) {
+	eng := engine.New(fake.New(fake.Script{}))
+
+	_, err := eng.Execute(context.Background(), modules.ExecuteRequest{
+		Language: "python",
+		Inputs:   []string{strings.Repeat("x", engine.MaxInputBytes/2), strings.Repeat("x", engine.MaxInputBytes/2)},
+	})
+	if !errors.Is(err, engine.ErrInputTooLarge) {
+		t.Fatalf("err = %v, want ErrInputTooLarge", err)
+	}
+}
+
+func TestExecuteUnsupportedLanguage(t *testing.T) {
+	eng := engine.New(fake.New(fake.Script{}))
+
+	_, err := eng.Execute(context.Background(), modules.ExecuteRequest{Language: "cobol"})
+	if err == nil {
+		t.Fatal("expected an error for an unknown language")
+	}
+}
+
+func TestExecuteInvalidProject(t *testing.T) {
+	eng := engine.New(fake.New(fake.Script{}))
+
+	_, err := eng.Execute(context.Background(), modules.ExecuteRequest{
+		Language: "python",
+		Files:    []modules.File{{Path: "main.py"}, {Path: "../escape.py"}},
+	})
+	if !errors.Is(err, project.ErrInvalid) {
+		t.Fatalf("err = %v, want project.ErrInvalid", err)
+	}
+}
+
+func TestStartFailure(t *testing.T) {
+	eng := engine.New(fake.New(fake.Script{StartErr: errors.New("no daemon")}))
+
+	sess, err := eng.StartSession(context.Background(), modules.ExecuteRequest{Language: "python"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	waitDone(t, sess)
+
+	snap := sess.Snapshot()
+	if snap.State != session.StateTerminated || snap.Reason != session.ReasonStartFailed {
+		t.Errorf("got state=%s reason=%s", snap.State, snap.Reason)
+	}
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	rt := fake.New(fake.Script{Hang: true})
+	eng := engine.New(rt)
+
+	var sessions []*session.Session
+	for i := 0; i < 12; i++ {
+		sess, err := eng.StartSession(context.Background(), modules.ExecuteRequest{Language: "python"})
+		if err != nil {
+			t.Fatalf("StartSession: %v", err)
+		}
+		sessions = append(sessions, sess)
+	}
+
+	waitFor(t, func() bool { return rt.Running() == 10 })
+
+	waiting := 0
+	for _, sess := range sessions {
+		if sess.GetState() == session.StateWaiting {
+			waiting++
+			if pos := eng.QueuePosition(sess.ID); pos < 1 || pos > 2 {
+				t.Errorf("queue position of waiting session = %d", pos)
+			}
+		}
+	}
+	if waiting != 2 {
+		t.Errorf("waiting sessions = %d, want 2", waiting)
+	}
+
+	// stopping running sessions lets the queued ones through
+	for _, sess := range sessions {
+		if _, err := eng.StopSession(context.Background(), sess.ID); err != nil {
+			t.Fatalf("StopSession: %v", err)
+		}
+	}
+	if err := eng.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if got := rt.MaxRunning(); got != 10 {
+		t.Errorf("max concurrent processes = %d, want 10", got)
+	}
+	for _, p := range rt.Processes() {
+		if !p.CleanedUp() {
+			t.Errorf("process %s was not cleaned up", p.ID())
+		}
+	}
+}
+
+// The idle timeout only counts once a session runs; a queued session
+// waits for as long as the queue allows.
+func TestQueuedSessionOutlivesIdleTimeout(t *testing.T) {
+	rt := fake.New(fake.Script{Hang: true})
+	eng := engine.New(rt)
+
+	var sessions []*session.Session
+	for i := 0; i < engine.MaxConcurrent+1; i++ {
+		sess, err := eng.StartSession(context.Background(), modules.ExecuteRequest{Language: "python"})
+		if err != nil {
+			t.Fatalf("StartSession: %v", err)
+		}
+		sessions = append(sessions, sess)
+	}
+	waitFor(t, func() bool { return rt.Running() == engine.MaxConcurrent })
+
+	// slots go to whichever goroutine gets there first
+	var queued *session.Session
+	for _, sess := range sessions {
+		if sess.GetState() == session.StateWaiting {
+			queued = sess
+		}
+	}
+	if queued == nil {
+		t.Fatal("no session is queued")
+	}
+	queued.SetIdleTimeout(20 * time.Millisecond)
+	time.Sleep(100 * time.Millisecond)
+
+	if state := queued.GetState(); state != session.StateWaiting {
+		t.Errorf("queued session state = %s, want %s", state, session.StateWaiting)
+	}
+	if pos := eng.QueuePosition(queued.ID); pos != 1 {
+		t.Errorf("queue position = %d, want 1", pos)
+	}
+
+	for _, sess := range sessions {
+		if _, err := eng.StopSession(context.Background(), sess.ID); err != nil {
+			t.Fatalf("StopSession: %v", err)
+		}
+	}
+	if err := eng.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+// warmingRuntime records the queue demand the engine reports.
+type warmingRuntime struct {
+	*fake.Runtime
+
+	mu   sync.Mutex
+	want map[string]int
+}
+
+func (w *warmingRuntime) Want(lang string, waiting int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.want[lang] = waiting
+}
+
+func (w *warmingRuntime) wanted(lang string) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.want[lang]
+}
+
+func (w *warmingRuntime) PoolStats() []sandbox.PoolStats {
+	return []sandbox.PoolStats{{Language: "python"}}
+}
+
+func TestWarmerSeesQueue(t *testing.T) {
+	rt := &warmingRuntime{
+		Runtime: fake.New(fake.Script{Hang: true}),
+		want:    map[string]int{},
+	}
+	eng := engine.New(rt)
+
+	var sessions []*session.Session
+	start := func(lang string) {
+		sess, err := eng.StartSession(context.Background(), modules.ExecuteRequest{Language: lang})
+		if err != nil {
+			t.Fatalf("StartSession: %v", err)
+		}
+		sessions = append(sessions, sess)
+	}
+
+	// fill every slot, then queue two python sessions and one java; the
+	// runtime sees the versions they resolve to
+	for i := 0; i < engine.MaxConcurrent; i++ {
+		start("javascript")
+	}
+	waitFor(t, func() bool { return rt.Running() == engine.MaxConcurrent })
+	start("python")
+	start("python@3.11")
+	start("java")
+
+	waitFor(t, func() bool { return rt.wanted("python@3.11") == 2 && rt.wanted("java@21") == 1 })
+
+	if got := eng.PoolStats(); len(got) != 1 {
+		t.Errorf("PoolStats = %v, want the runtime's stats", got)
+	}
+
+	for _, sess := range sessions {
+		if _, err := eng.StopSession(context.Background(), sess.ID); err != nil {
+			t.Fatalf("StopSession: %v", err)
+		}
+	}
+	if err := eng.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	if rt.wanted("python@3.11") != 0 || rt.wanted("java@21") != 0 {
+		t.Errorf("demand after drain = python %d, java %d, want 0", rt.wanted("python@3.11"), rt.wanted("java@21"))
+	}
+}
+
+func TestShutdownDrains(t *testing.T) {
+	eng := engine.New(fake.New(fake.Script{
+		Steps: []fake.Step{{Delay: 100 * time.Millisecond, Stdout: "done\n"}},
+	}))
+
+	sess, err := eng.StartSession(context.Background(), modules.ExecuteRequest{Language: "python"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+
+	if err := eng.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := sess.GetState(); got != session.StateFinished {
+		t.Errorf("state after shutdown = %s, want %s", got, session.StateFinished)
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	eng := engine.New(fake.New(fake.Script{Hang: true}))
+
+	sess, err := eng.StartSession(context.Background(), modules.ExecuteRequest{Language: "python"})
+	if err != nil {
+		t.Fatalf("StartSession: %v", err)
+	}
+	defer sess.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := eng.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v, want deadline exceeded", err)
+	}
+}
+
+func waitDone(t *testing.T, sess *session.Session) {
+	t.Helper()
+	select {
+	case <-sess.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("session %s did not finish", sess.ID)
+	}
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -2,7 +2,11 @@ package engine
 
 import (
 	"context"
+	"fmt"
+	"strings"
+
 	"execution-engine/internal/modules"
+	"execution-engine/internal/session"
 )
 
 type Executor interface {
@@ -12,4 +16,89 @@ type Executor interface {
 		code string,
 		inputs []string,
 	) (*modules.ExecuteResult, error)
-}
\ No newline at end of file
+}
+
+func (e *engineImpl) Run(
+	ctx context.Context,
+	lang string,
+	code string,
+	inputs []string,
+) (*modules.ExecuteResult, error) {
+	return e.Execute(ctx, modules.ExecuteRequest{
+		Language: lang,
+		Code:     code,
+		Inputs:   inputs,
+	})
+}
+
+func (e *engineImpl) Execute(
+	ctx context.Context,
+	req modules.ExecuteRequest,
+) (*modules.ExecuteResult, error) {
+
+	sess, err := e.startSession(req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-sess.Done():
+	case <-ctx.Done():
+		// caller gave up, don't leave the container running; stopping may
+		// wait on a busy session, the caller doesn't
+		go sess.Stop()
+		return nil, ctx.Err()
+	}
+
+	res := resultOf(sess)
+	if len(sess.ArtifactPatterns) == 0 {
+		// with artifacts the caller still needs the session to fetch them
+		e.sessions.MarkFetched(sess.ID)
+	}
+	return res, nil
+}
+
+// validateInputs rejects inputs feedInputs would take too long to write.
+func validateInputs(inputs []string) error {
+	total := 0
+	for _, in := range inputs {
+		total += len(in) + 1 // the newline feedInputs may add
+	}
+	if total > MaxInputBytes {
+		return fmt.Errorf("%w: more than %d bytes", ErrInputTooLarge, MaxInputBytes)
+	}
+	return nil
+}
+
+// feedInputs writes the request inputs to the program's stdin, one per line.
+func feedInputs(sess *session.Session, inputs []string) error {
+	for _, in := range inputs {
+		if !strings.HasSuffix(in, "\n") {
+			in += "\n"
+		}
+		if err := sess.WriteInput(in); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resultOf(sess *session.Session) *modules.ExecuteResult {
+	snap := sess.Snapshot()
+	compileStdout, compileStderr := sess.GetCompileOutput()
+
+	return &modules.ExecuteResult{
+		SessionID:  snap.ID,
+		ExitCode:   snap.ReportedExitCode(),
+		Stdout:     sess.GetStdout(),
+		Stderr:     sess.GetStderr(),
+		Output:     sess.GetCombinedOutput(),
+		DurationMs: sess.Duration().Milliseconds(),
+		TimedOut:   snap.State == session.StateTimedOut,
+		OOMKilled:  snap.OOMKilled,
+		Reason:     string(snap.Reason),
+
+		CompileStdout: compileStdout,
+		CompileStderr: compileStderr,
+	}
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"execution-engine/internal/artifact"
+	"execution-engine/internal/sandbox"
+	"execution-engine/internal/session"
+)
+
+// outputDrainTimeout bounds how long we wait for the output stream to
+// flush after the program has exited.
+const outputDrainTimeout = 2 * time.Second
+
+// supervise drives a started process to completion: it streams output into
+// the session, kills the process when the session is cancelled, records
+// how it exited and always cleans it up.
+func (e *engineImpl) supervise(sess *session.Session, proc sandbox.Process) {
+	defer sess.SignalCleanup() // 🔥 Signal cleanup when done
+	defer func() {
+		if err := proc.Cleanup(context.Background()); err != nil {
+			log.Printf("Engine: cleanup of session %s failed: %v", sess.ID, err)
+		}
+	}()
+
+	// ---------------- stream output ----------------
+	copyDone := make(chan struct{})
+	go func() {
+		defer close(copyDone)
+		_ = proc.Stream(sess.StdoutWriter(), sess.StderrWriter())
+	}()
+
+	type waitResult struct {
+		status session.ExitStatus
+		err    error
+	}
+	waitCh := make(chan waitResult, 1)
+	go func() {
+		status, err := proc.Wait(context.Background())
+		waitCh <- waitResult{status, err}
+	}()
+
+	select {
+	case res := <-waitCh:
+		if res.err != nil {
+			log.Printf("Engine: wait for session %s failed: %v", sess.ID, res.err)
+			sess.MarkTerminated(session.ReasonCancelled)
+			return
+		}
+
+		// make sure every byte written before exit reaches the session
+		select {
+		case <-copyDone:
+		case <-time.After(outputDrainTimeout):
+		}
+		collectArtifacts(sess, proc)
+		sess.MarkFinished(res.status)
+
+	case <-sess.Context().Done(): // 🔥 session cancelled
+		if err := proc.Kill(context.Background()); err != nil {
+			log.Printf("Engine: kill of session %s failed: %v", sess.ID, err)
+		}
+		// partial results of a program that ran out of time are still useful
+		collectArtifacts(sess, proc)
+		sess.MarkTerminated(session.ReasonCancelled)
+	}
+}
+
+// collectArtifacts keeps the files the session asked for before the
+// workspace is cleaned up. A program that exits has them in place before
+// its final state is published; a killed one only after, so readers wait
+// for CleanupDone.
+func collectArtifacts(sess *session.Session, proc sandbox.Process) {
+	if len(sess.ArtifactPatterns) == 0 {
+		return
+	}
+	ws, ok := proc.(sandbox.Workspace)
+	if !ok || ws.WorkspaceDir() == "" {
+		log.Printf("Engine: session %s wants artifacts but its sandbox has no workspace", sess.ID)
+		return
+	}
+
+	kept, skipped, err := artifact.Collect(ws.WorkspaceDir(), sess.ArtifactPatterns, artifact.DefaultLimits)
+	if err != nil {
+		log.Printf("Engine: collecting artifacts of session %s failed: %v", sess.ID, err)
+	}
+	sess.SetArtifacts(kept, skipped)
+}
@@ -0,0 +1,158 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+)
+
+// ImageSource says where PreloadImages gets images the daemon doesn't
+// have yet.
+type ImageSource struct {
+	// ArchiveDir holds `docker save` tarballs (optionally gzip, bzip2 or
+	// xz compressed) that are loaded before anything is pulled.
+	ArchiveDir string
+	// NoPull forbids pulling from a registry, for air-gapped hosts.
+	NoPull bool
+}
+
+// WithImageSource sets where missing images come from. By default they
+// are pulled.
+func WithImageSource(src ImageSource) DockerOption {
+	return func(d *DockerExecutor) {
+		d.images = src
+	}
+}
+
+var archiveExts = []string{".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tar.xz"}
+
+// loadedArchives remembers the tarballs already loaded, so retries only
+// load new or replaced ones. The zero value is ready to use.
+type loadedArchives struct {
+	mu     sync.Mutex
+	stamps map[string]archiveStamp
+}
+
+// archiveStamp tells a replaced tarball from the one loaded before.
+type archiveStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+func stampOf(info fs.FileInfo) archiveStamp {
+	return archiveStamp{size: info.Size(), modTime: info.ModTime()}
+}
+
+func (l *loadedArchives) has(name string, info fs.FileInfo) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	st, ok := l.stamps[name]
+	return ok && st.size == info.Size() && st.modTime.Equal(info.ModTime())
+}
+
+func (l *loadedArchives) add(name string, info fs.FileInfo) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.stamps == nil {
+		l.stamps = make(map[string]archiveStamp)
+	}
+	l.stamps[name] = stampOf(info)
+}
+
+// loadArchives loads tarballs from dir, in name order, until none of
+// images is missing. An image may be in any tarball, so there is no
+// telling which one to load without loading it. A tarball that fails to
+// load is logged and skipped; one in loaded is not loaded again.
+func loadArchives(
+	ctx context.Context,
+	cli *client.Client,
+	dir string,
+	images []string,
+	loaded *loadedArchives,
+) error {
+
+	dirents, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("image archives: %w", err)
+	}
+
+	missing := missingImages(ctx, cli, images)
+	for _, d := range dirents {
+		if len(missing) == 0 {
+			return nil
+		}
+		if !d.Type().IsRegular() || !isArchive(d.Name()) {
+			continue
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			log.Printf("⚠️  image archive %s: %v", d.Name(), err)
+			continue
+		}
+		if loaded.has(d.Name(), info) {
+			continue
+		}
+
+		path := filepath.Join(dir, d.Name())
+		log.Printf("📦 loading image archive %s", path)
+		if err := loadArchive(ctx, cli, path); err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			// one bad file must not keep the rest from loading
+			log.Printf("⚠️  %v", err)
+			continue
+		}
+		loaded.add(d.Name(), info)
+		missing = missingImages(ctx, cli, missing)
+	}
+	return nil
+}
+
+func loadArchive(ctx context.Context, cli *client.Client, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	res, err := cli.ImageLoad(ctx, f, client.ImageLoadWithQuiet(true))
+	if err != nil {
+		return fmt.Errorf("load image archive %s: %w", path, err)
+	}
+	defer res.Body.Close()
+
+	if err := drainJSON(res.Body); err != nil {
+		return fmt.Errorf("load image archive %s: %w", path, err)
+	}
+	return nil
+}
+
+// missingImages returns the images the daemon doesn't have.
+func missingImages(ctx context.Context, cli *client.Client, images []string) []string {
+	var missing []string
+	for _, name := range images {
+		if _, err := cli.ImageInspect(ctx, name); err != nil {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+func isArchive(name string) bool {
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
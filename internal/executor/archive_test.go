@@ -0,0 +1,40 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLoadedArchives(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "images.tar")
+	if err := os.WriteFile(path, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var loaded loadedArchives
+	if loaded.has("images.tar", info) {
+		t.Fatal("archive loaded before add")
+	}
+	loaded.add("images.tar", info)
+	if !loaded.has("images.tar", info) {
+		t.Fatal("archive not remembered")
+	}
+
+	// a replaced tarball is loaded again
+	later := info.ModTime().Add(time.Second)
+	if err := os.Chtimes(path, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if info, err = os.Stat(path); err != nil {
+		t.Fatal(err)
+	}
+	if loaded.has("images.tar", info) {
+		t.Error("replaced archive counted as loaded")
+	}
+}
@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+
+	"execution-engine/internal/buildcache"
+	"execution-engine/internal/language"
+	"execution-engine/internal/project"
+	"execution-engine/internal/session"
+)
+
+// WithBuildCache reuses compiled programs from c when the same code is
+// submitted again. A nil cache disables it.
+func WithBuildCache(c *buildcache.Cache) DockerOption {
+	return func(d *DockerExecutor) {
+		d.builds = c
+	}
+}
+
+// build returns a workspace holding s's compiled program, taking it from the
+// build cache when the same code was compiled before. The caller removes it.
+func (d *DockerExecutor) build(
+	ctx context.Context,
+	s *session.Session,
+	spec language.Spec,
+	runtime string,
+) (string, error) {
+
+	if d.builds == nil {
+		return d.compile(ctx, s, spec, runtime)
+	}
+
+	key, err := d.buildKey(ctx, s, spec)
+	if err != nil {
+		log.Printf("Executor: build cache disabled for session %s: %v", s.ID, err)
+		return d.compile(ctx, s, spec, runtime)
+	}
+
+	dir, err := d.cachedBuild(s, key)
+	if err != nil {
+		log.Printf("Executor: build cache load for session %s: %v", s.ID, err)
+	}
+	if dir != "" {
+		return dir, nil
+	}
+
+	dir, err = d.compile(ctx, s, spec, runtime)
+	if err != nil {
+		// failed builds are not cached; the compiler reports them again
+		return "", err
+	}
+
+	stdout, stderr := s.GetCompileOutput()
+	err = d.builds.Store(key, stdout, stderr, func(dst string) error {
+		return copyTree(dir, dst)
+	})
+	if err != nil {
+		log.Printf("Executor: build cache store for session %s: %v", s.ID, err)
+	}
+	return dir, nil
+}
+
+// buildKey identifies s's build by the exact image the compiler runs in,
+// so updating an image under the same tag invalidates its builds.
+func (d *DockerExecutor) buildKey(
+	ctx context.Context,
+	s *session.Session,
+	spec language.Spec,
+) (buildcache.Key, error) {
+
+	img, err := d.cli.ImageInspect(ctx, imageRef(spec))
+	if err != nil {
+		return buildcache.Key{}, err
+	}
+	return compileKey(spec, img.ID, s.Project), nil
+}
+
+// compileKey is everything about compiling p with spec in the image with
+// imageID that can change the build.
+func compileKey(spec language.Spec, imageID string, p project.Project) buildcache.Key {
+	return buildcache.Key{
+		Language:   spec.ID(),
+		Image:      imageID,
+		CompileCmd: spec.Expand(spec.CompileCmd, p),
+		Env:        envList(spec.Env),
+		Source:     p.Fingerprint(),
+	}
+}
+
+// cachedBuild copies the build cached for key into a fresh workspace and
+// replays the compiler's output into s, as if it had just compiled. It
+// returns "" on a miss.
+func (d *DockerExecutor) cachedBuild(s *session.Session, key buildcache.Key) (string, error) {
+	dir, err := os.MkdirTemp("", "build-*")
+	if err != nil {
+		return "", err
+	}
+
+	var out buildcache.Build
+	hit, err := d.builds.Load(key, func(b buildcache.Build) error {
+		out = b
+		return copyTree(b.Dir, dir)
+	})
+	if !hit || err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	s.MarkCompiling()
+	if out.Stdout != "" {
+		_, _ = io.WriteString(s.CompileStdoutWriter(), out.Stdout)
+	}
+	if out.Stderr != "" {
+		_, _ = io.WriteString(s.CompileStderrWriter(), out.Stderr)
+	}
+	return dir, nil
+}
@@ -0,0 +1,29 @@
+package executor
+
+import (
+	"testing"
+
+	"execution-engine/internal/language"
+)
+
+func TestCompileKeyCoversEnv(t *testing.T) {
+	spec, err := language.Resolve("go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := spec.DefaultProject()
+
+	cgo := spec
+	cgo.Env = map[string]string{}
+	for k, v := range spec.Env {
+		cgo.Env[k] = v
+	}
+	cgo.Env["CGO_ENABLED"] = "1"
+
+	if compileKey(spec, "sha256:img", p).Hash() == compileKey(cgo, "sha256:img", p).Hash() {
+		t.Error("specs differing only in Env share a build")
+	}
+	if compileKey(spec, "sha256:img", p).Hash() != compileKey(spec, "sha256:img", p).Hash() {
+		t.Error("key is not deterministic")
+	}
+}
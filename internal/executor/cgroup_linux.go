@@ -0,0 +1,109 @@
+package executor
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cgroup is a cgroup v2 leaf holding one sandbox.
+type cgroup struct {
+	path string
+	dir  *os.File // passed to clone via SysProcAttr.CgroupFD
+}
+
+// enableControllers makes the memory, cpu and pids controllers available
+// to cgroups created under root.
+func enableControllers(root string) error {
+	if _, err := os.Stat(filepath.Join(root, "cgroup.controllers")); err != nil {
+		return fmt.Errorf("%s is not a cgroup v2 directory: %w", root, err)
+	}
+	return os.WriteFile(
+		filepath.Join(root, "cgroup.subtree_control"),
+		[]byte("+memory +cpu +pids"),
+		0644,
+	)
+}
+
+// newCgroup creates a cgroup under root with lim applied.
+func newCgroup(root, name string, lim limits) (*cgroup, error) {
+	path := filepath.Join(root, name)
+	if err := os.Mkdir(path, 0755); err != nil {
+		return nil, fmt.Errorf("create cgroup: %w", err)
+	}
+
+	cg := &cgroup{path: path}
+
+	limits := []struct {
+		file     string
+		value    string
+		optional bool
+	}{
+		{"memory.max", strconv.FormatInt(lim.memory, 10), false},
+		{"memory.swap.max", "0", true}, // absent without swap accounting
+		{"cpu.max", fmt.Sprintf("%d %d", lim.nanoCPUs/10_000, 100_000), false},
+		{"pids.max", strconv.FormatInt(lim.pids, 10), false},
+	}
+	for _, l := range limits {
+		err := os.WriteFile(filepath.Join(path, l.file), []byte(l.value), 0644)
+		if err != nil && !l.optional {
+			cg.remove()
+			return nil, fmt.Errorf("cgroup %s: %w", l.file, err)
+		}
+	}
+
+	dir, err := os.Open(path)
+	if err != nil {
+		cg.remove()
+		return nil, fmt.Errorf("open cgroup: %w", err)
+	}
+	cg.dir = dir
+
+	return cg, nil
+}
+
+// oomKilled reports whether the kernel OOM killer fired inside the cgroup.
+func (c *cgroup) oomKilled() bool {
+	f, err := os.Open(filepath.Join(c.path, "memory.events"))
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && fields[0] == "oom_kill" {
+			n, _ := strconv.Atoi(fields[1])
+			return n > 0
+		}
+	}
+	return false
+}
+
+// kill SIGKILLs every process in the cgroup (linux 5.14+).
+func (c *cgroup) kill() error {
+	return os.WriteFile(filepath.Join(c.path, "cgroup.kill"), []byte("1"), 0644)
+}
+
+// remove deletes the cgroup, retrying briefly while the kernel finishes
+// tearing down its last processes.
+func (c *cgroup) remove() error {
+	if c.dir != nil {
+		c.dir.Close()
+	}
+
+	var err error
+	for i := 0; i < 10; i++ {
+		if err = os.Remove(c.path); err == nil || errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	return fmt.Errorf("remove cgroup: %w", err)
+}
@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"sort"
+	"time"
+
+	"execution-engine/internal/language"
+)
+
+const workspaceDir = "/workspace"
+
+// limits bound a single sandboxed process.
+type limits struct {
+	memory   int64 // bytes
+	nanoCPUs int64
+	pids     int64
+	tmpfs    string        // size of the writable scratch space
+	timeout  time.Duration // zero leaves it to the session's time limit
+}
+
+// runLimits apply to the user's program on every backend.
+func runLimits(spec language.Spec) limits {
+	return limits{
+		memory:   spec.Effective().Memory,
+		nanoCPUs: 500_000_000, // 0.5 core
+		pids:     32,
+		tmpfs:    "32m",
+	}
+}
+
+// compileLimits apply to the compile step, which runs before the program
+// and outside its time budget. Compilers need more room than most programs.
+func compileLimits(spec language.Spec) limits {
+	spec = spec.Effective()
+	return limits{
+		memory:   spec.CompileMemory,
+		nanoCPUs: 1_000_000_000,
+		pids:     64,
+		tmpfs:    "128m",
+		timeout:  spec.CompileTimeout,
+	}
+}
+
+// compileDrainTimeout bounds how long compiler output may trail the
+// compiler's exit.
+const compileDrainTimeout = 2 * time.Second
+
+// envList turns a spec's environment into KEY=value pairs in a stable
+// order.
+func envList(env map[string]string) []string {
+	list := make([]string, 0, len(env))
+	for k, v := range env {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list
+}
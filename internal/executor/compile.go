@@ -0,0 +1,107 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+
+	"execution-engine/internal/language"
+	"execution-engine/internal/sandbox"
+	"execution-engine/internal/session"
+)
+
+// compile builds s's code in a container of its own, under the compile
+// limits, and returns the workspace holding the build output. The caller
+// removes it.
+func (d *DockerExecutor) compile(
+	ctx context.Context,
+	s *session.Session,
+	spec language.Spec,
+	runtime string,
+) (string, error) {
+
+	s.MarkCompiling()
+
+	lim := compileLimits(spec)
+	proc, err := d.create(ctx, containerSpec{
+		image:   imageRef(spec),
+		cmd:     spec.Expand(spec.CompileCmd, s.Project),
+		env:     envList(spec.Env),
+		limits:  lim,
+		runtime: runtime,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// the workspace outlives the compile container
+	dir := proc.tempDir
+	proc.tempDir = ""
+	defer proc.Cleanup(context.Background())
+
+	fail := func(err error) (string, error) {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	if err := s.Project.Write(dir); err != nil {
+		return fail(err)
+	}
+
+	if err := d.cli.ContainerStart(ctx, proc.containerID, container.StartOptions{}); err != nil {
+		return fail(fmt.Errorf("compile container start: %w", err))
+	}
+
+	if err := awaitBuild(ctx, s, proc, lim); err != nil {
+		return fail(err)
+	}
+	return dir, nil
+}
+
+// awaitBuild streams a started compile process's output into s and waits
+// for it, enforcing the compile timeout. A failed build is a
+// *sandbox.CompileError.
+func awaitBuild(
+	ctx context.Context,
+	s *session.Session,
+	proc sandbox.Process,
+	lim limits,
+) error {
+
+	streamed := make(chan struct{})
+	go func() {
+		defer close(streamed)
+		_ = proc.Stream(s.CompileStdoutWriter(), s.CompileStderrWriter())
+	}()
+
+	waitCtx, cancel := context.WithTimeout(ctx, lim.timeout)
+	defer cancel()
+
+	status, err := proc.Wait(waitCtx)
+	if err != nil {
+		_ = proc.Kill(context.Background())
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case waitCtx.Err() != nil:
+			return &sandbox.CompileError{ExitCode: 137, TimedOut: true}
+		}
+		return err
+	}
+
+	select {
+	case <-streamed:
+	case <-time.After(compileDrainTimeout):
+	}
+
+	if status.ExitCode != 0 || status.OOMKilled {
+		return &sandbox.CompileError{
+			ExitCode:  status.ExitCode,
+			OOMKilled: status.OOMKilled,
+		}
+	}
+	return nil
+}
@@ -1,14 +1,40 @@
 package executor
 
 import (
+	"context"
+
 	"github.com/docker/docker/client"
+
+	"execution-engine/internal/buildcache"
+	"execution-engine/internal/sandbox"
 )
 
+var _ sandbox.Runtime = (*DockerExecutor)(nil)
+
 type DockerExecutor struct {
-	cli *client.Client
+	cli      *client.Client
+	runtimes OCIRuntimes
+	pool     *warmPool         // nil when disabled
+	builds   *buildcache.Cache // nil when disabled
+	images   ImageSource
+	archives loadedArchives
+
+	// set by StartImageRetry
+	retryCancel context.CancelFunc
+	retryDone   chan struct{}
+}
+
+// DockerOption customizes a DockerExecutor built by NewDockerExecutor.
+type DockerOption func(*DockerExecutor)
+
+// WithOCIRuntimes selects the OCI runtimes containers run under.
+func WithOCIRuntimes(r OCIRuntimes) DockerOption {
+	return func(d *DockerExecutor) {
+		d.runtimes = r
+	}
 }
 
-func NewDockerExecutor() (*DockerExecutor, error) {
+func NewDockerExecutor(opts ...DockerOption) (*DockerExecutor, error) {
 	cli, err := client.NewClientWithOpts(
 		client.FromEnv,
 		client.WithAPIVersionNegotiation(),
@@ -16,5 +42,10 @@ func NewDockerExecutor() (*DockerExecutor, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &DockerExecutor{cli: cli}, nil
+
+	d := &DockerExecutor{cli: cli}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d, nil
 }
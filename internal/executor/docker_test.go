@@ -0,0 +1,106 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"execution-engine/internal/language"
+	"execution-engine/internal/project"
+	"execution-engine/internal/session"
+)
+
+func TestContainerConfigStdinOnce(t *testing.T) {
+	tests := []struct {
+		name      string
+		cs        containerSpec
+		stdinOnce bool
+	}{
+		{"attached", containerSpec{stdin: true}, true},
+		{"tty", containerSpec{stdin: true, tty: true}, false},
+		{"compile", containerSpec{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cs.config().StdinOnce; got != tt.stdinOnce {
+				t.Errorf("StdinOnce = %v, want %v", got, tt.stdinOnce)
+			}
+		})
+	}
+}
+
+// dockerForTest returns an executor on the local daemon with spec's image
+// present, skipping the test when there is none.
+func dockerForTest(t *testing.T, spec language.Spec) *DockerExecutor {
+	t.Helper()
+	if testing.Short() {
+		t.Skip("needs a Docker daemon")
+	}
+
+	d, err := NewDockerExecutor()
+	if err != nil {
+		t.Skipf("no Docker client: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	if _, err := d.cli.Ping(ctx); err != nil {
+		t.Skipf("no Docker daemon: %v", err)
+	}
+	if err := d.ensureImage(ctx, spec.Image); err != nil {
+		t.Skipf("no image %s: %v", spec.Image, err)
+	}
+	return d
+}
+
+// Closing stdin must EOF the program through the real attach, and keep the
+// output it writes afterwards.
+func TestDockerCloseStdin(t *testing.T) {
+	spec, err := language.Resolve("python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := dockerForTest(t, spec)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	s := session.NewPending(session.NewID(), spec.ID(), project.Project{
+		Files: []project.File{{
+			Path: spec.FileName,
+			Data: []byte("import sys\nprint(len(sys.stdin.read()))\nprint('after eof')\n"),
+		}},
+		Entry: spec.FileName,
+	})
+	defer s.Stop()
+
+	proc, err := d.Start(ctx, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proc.Cleanup(context.Background())
+
+	var stdout, stderr bytes.Buffer
+	streamed := make(chan error, 1)
+	go func() { streamed <- proc.Stream(&stdout, &stderr) }()
+
+	stdin := proc.Stdin()
+	if _, err := stdin.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := stdin.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	status, err := proc.Wait(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := <-streamed; err != nil {
+		t.Fatal(err)
+	}
+
+	if status.ExitCode != 0 || stdout.String() != "6\nafter eof\n" {
+		t.Errorf("exit %d, stdout %q, stderr %q", status.ExitCode, stdout.String(), stderr.String())
+	}
+}
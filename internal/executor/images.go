@@ -3,29 +3,32 @@ package executor
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
+
+	"execution-engine/internal/language"
 )
 
 // ensureImage ensures the Docker image exists locally.
-// If not present, it pulls it.
-func ensureImage(
-	ctx context.Context,
-	cli *client.Client,
-	imageName string,
-) error {
+// If not present, it pulls it, unless pulls are disabled.
+func (d *DockerExecutor) ensureImage(ctx context.Context, imageName string) error {
 
 	// 1️⃣ Check if image already exists
-	_, _, err := cli.ImageInspectWithRaw(ctx, imageName)
+	_, _, err := d.cli.ImageInspectWithRaw(ctx, imageName)
 	if err == nil {
 		return nil
 	}
+	if d.images.NoPull {
+		return fmt.Errorf("image %s is not available locally and pulls are disabled", imageName)
+	}
 
 	// 2️⃣ Pull image
-	reader, err := cli.ImagePull(
+	reader, err := d.cli.ImagePull(
 		ctx,
 		imageName,
 		image.PullOptions{},
@@ -36,16 +39,119 @@ func ensureImage(
 	defer reader.Close()
 
 	// 3️⃣ Drain pull output (required)
-	dec := json.NewDecoder(reader)
+	if err := drainJSON(reader); err != nil {
+		return fmt.Errorf("failed to pull image %s: %w", imageName, err)
+	}
+
+	return nil
+}
+
+// drainJSON reads a daemon progress stream to the end, returning the
+// error it reports, if any.
+func drainJSON(r io.Reader) error {
+	dec := json.NewDecoder(r)
 	for {
-		var msg map[string]interface{}
+		var msg struct {
+			Error string `json:"error"`
+		}
 		if err := dec.Decode(&msg); err != nil {
 			if err == io.EOF {
-				break
+				return nil
 			}
-			return fmt.Errorf("image pull decode error: %w", err)
+			return fmt.Errorf("decode progress: %w", err)
+		}
+		if msg.Error != "" {
+			return errors.New(msg.Error)
 		}
 	}
+}
 
-	return nil
+// resolveImage inspects spec's local image and, when the spec pins a
+// digest, finds the local image with that content.
+func resolveImage(
+	ctx context.Context,
+	cli *client.Client,
+	spec language.Spec,
+) (language.ImageStatus, error) {
+
+	if spec.Digest == "" {
+		img, err := cli.ImageInspect(ctx, spec.Image)
+		if err != nil {
+			return language.ImageStatus{}, fmt.Errorf("inspect image %s: %w", spec.Image, err)
+		}
+		st, _ := matchImage(spec, img.ID, img.RepoDigests)
+		return st, nil
+	}
+
+	// the tag may name other content than a pull by digest fetched, and an
+	// image ID pin is found by the ID alone
+	mismatch := fmt.Errorf("language %s: no local image %s with digest %s", spec.ID(), spec.Image, spec.Digest)
+	for _, ref := range []string{spec.Image, pinnedRef(spec.Image, spec.Digest), spec.Digest} {
+		img, err := cli.ImageInspect(ctx, ref)
+		if err != nil {
+			continue
+		}
+		st, ok := matchImage(spec, img.ID, img.RepoDigests)
+		if ok {
+			return st, nil
+		}
+		mismatch = fmt.Errorf(
+			"language %s: image %s is %s, want %s",
+			spec.ID(), ref, st.Digest, spec.Digest,
+		)
+	}
+	return language.ImageStatus{}, mismatch
+}
+
+// matchImage describes the image with id and repoDigests, and reports
+// whether it is the content spec pins, if it pins any.
+func matchImage(spec language.Spec, id string, repoDigests []string) (language.ImageStatus, bool) {
+	st := language.ImageStatus{ID: id, Digest: id}
+	if len(repoDigests) > 0 {
+		st.Digest = digestOf(repoDigests[0])
+	}
+	if spec.Digest == "" {
+		return st, true
+	}
+
+	if id == spec.Digest {
+		st.Digest, st.Pinned = id, true
+		return st, true
+	}
+	for _, rd := range repoDigests {
+		if digestOf(rd) == spec.Digest {
+			st.Digest, st.Pinned = spec.Digest, true
+			return st, true
+		}
+	}
+	return st, false
+}
+
+// pinnedRef is image's repository at digest, dropping any tag.
+func pinnedRef(image, digest string) string {
+	repo, _, _ := strings.Cut(image, "@")
+	// a colon after the last slash starts the tag; before it, a port
+	if i := strings.LastIndexByte(repo, ':'); i > strings.LastIndexByte(repo, '/') {
+		repo = repo[:i]
+	}
+	return repo + "@" + digest
+}
+
+// digestOf strips the repository from a "repo@sha256:..." reference.
+func digestOf(repoDigest string) string {
+	if i := strings.LastIndexByte(repoDigest, '@'); i >= 0 {
+		return repoDigest[i+1:]
+	}
+	return repoDigest
+}
+
+// imageRef is the image spec's containers are created from: the verified
+// image ID for pinned specs, so retagging can't swap their content.
+func imageRef(spec language.Spec) string {
+	if spec.Digest != "" {
+		if st, ok := language.ImageStatusOf(spec.ID()); ok && st.Pinned {
+			return st.ID
+		}
+	}
+	return spec.Image
 }
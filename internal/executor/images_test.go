@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"testing"
+
+	"execution-engine/internal/language"
+)
+
+func TestPinnedRef(t *testing.T) {
+	const digest = "sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		image, want string
+	}{
+		{"gcc:14", "gcc@" + digest},
+		{"python", "python@" + digest},
+		{"localhost:5000/lang/python:3.12", "localhost:5000/lang/python@" + digest},
+		{"localhost:5000/python", "localhost:5000/python@" + digest},
+		{"ruby:3.3@sha256:ffff", "ruby@" + digest},
+	}
+	for _, tt := range tests {
+		if got := pinnedRef(tt.image, digest); got != tt.want {
+			t.Errorf("pinnedRef(%q) = %q, want %q", tt.image, got, tt.want)
+		}
+	}
+}
+
+func TestMatchImage(t *testing.T) {
+	const (
+		id   = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+		repo = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	)
+	repoDigests := []string{"gcc@" + repo}
+
+	tests := []struct {
+		name   string
+		digest string
+		want   language.ImageStatus
+		ok     bool
+	}{
+		{"unpinned", "", language.ImageStatus{ID: id, Digest: repo}, true},
+		{"repo digest", repo, language.ImageStatus{ID: id, Digest: repo, Pinned: true}, true},
+		{"image id", id, language.ImageStatus{ID: id, Digest: id, Pinned: true}, true},
+		{"mismatch", "sha256:3333", language.ImageStatus{ID: id, Digest: repo}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := language.Spec{Name: "c", Version: "14", Image: "gcc:14", Digest: tt.digest}
+			got, ok := matchImage(spec, id, repoDigests)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("matchImage = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"execution-engine/internal/language"
+	"execution-engine/internal/sandbox"
+)
+
+const (
+	// sandboxInitArg re-executes the server binary as the init process of
+	// a local sandbox. See MaybeRunSandboxInit.
+	sandboxInitArg = "__sandbox_init"
+
+	// sandboxInitEnv carries the JSON encoded initConfig to the init process.
+	sandboxInitEnv = "SANDBOX_INIT_CONFIG"
+
+	// DefaultCgroupRoot is the cgroup v2 directory local sandboxes are
+	// created under. It must be delegated to the user running the server.
+	DefaultCgroupRoot = "/sys/fs/cgroup/execution-engine"
+)
+
+// LocalExecutor runs language commands directly on the host, isolated with
+// user, pid, mount, network, UTS and IPC namespaces, cgroup v2 limits and a
+// seccomp filter instead of a Docker container. The language toolchains
+// must be installed on the host.
+type LocalExecutor struct {
+	cgroupRoot string
+}
+
+var _ sandbox.Runtime = (*LocalExecutor)(nil)
+
+// initConfig tells the sandbox init process what to set up and run.
+type initConfig struct {
+	RootDir  string   // empty dir the private root is built on
+	RootSize string   // tmpfs size of the private root
+	SrcDir   string   // host dir holding the files to copy into /workspace
+	Cmd      []string // command to run inside /workspace
+	Env      []string // KEY=value pairs added to the base environment
+	// BindSrc mounts SrcDir itself as /workspace, so what the command
+	// writes there (a build) outlives the sandbox.
+	BindSrc bool
+	// Hold keeps the init, and with it the size-limited /workspace, after
+	// the program exits until the host closes fd 4, so the host can read
+	// the workspace through /proc/<init>/root.
+	Hold bool
+}
+
+// initStatus is reported by the sandbox init process just before it exits.
+type initStatus struct {
+	ExitCode int `json:"exitCode"`
+}
+
+// restrictToDefaults marks every version but each language's default
+// unavailable. The local sandbox runs the host's toolchain whatever image
+// a spec names, so python@3.8 and python@3.12 would silently be the same.
+func restrictToDefaults() {
+	for _, spec := range language.AllSpecs() {
+		if !language.IsDefault(spec) {
+			language.SetUnavailable(spec.ID(), "needs its image, which the local sandbox doesn't use; use the default version")
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// host directories bind mounted read-only into every local sandbox
+var hostDirs = []string{"/bin", "/sbin", "/usr", "/lib", "/lib32", "/lib64"}
+
+// hostEtc are the parts of the host's /etc toolchains need: the linker
+// cache, Debian style alternatives links and JDK configuration. Globs.
+var hostEtc = []string{"ld.so.cache", "alternatives", "java-*", "localtime"}
+
+// etcStubs stand in for the host's account and network files.
+var etcStubs = map[string]string{
+	"passwd": "root:x:0:0:root:/tmp:/bin/sh\nnobody:x:65534:65534:nobody:/nonexistent:/usr/sbin/nologin\n",
+	"group":  "root:x:0:\nnogroup:x:65534:\n",
+	"hosts":  "127.0.0.1 localhost sandbox\n::1 localhost\n",
+}
+
+var hostDevices = []string{"/dev/null", "/dev/zero", "/dev/random", "/dev/urandom"}
+
+const sandboxPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// MaybeRunSandboxInit turns the process into a local sandbox init when the
+// server binary was re-executed by LocalExecutor, and never returns in that
+// case. It must be called first thing in main.
+func MaybeRunSandboxInit() {
+	if len(os.Args) < 2 || os.Args[1] != sandboxInitArg {
+		return
+	}
+
+	// mounts, capabilities and the seccomp filter are per thread state the
+	// program inherits from the thread that forks it
+	runtime.LockOSThread()
+
+	code, err := sandboxInit()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+		os.Exit(127)
+	}
+	os.Exit(code)
+}
+
+func sandboxInit() (int, error) {
+	var cfg initConfig
+	if err := json.Unmarshal([]byte(os.Getenv(sandboxInitEnv)), &cfg); err != nil {
+		return 0, fmt.Errorf("config: %w", err)
+	}
+	if len(cfg.Cmd) == 0 {
+		return 0, fmt.Errorf("config: empty command")
+	}
+
+	if err := buildRoot(cfg); err != nil {
+		return 0, err
+	}
+	if err := unix.Sethostname([]byte("sandbox")); err != nil {
+		return 0, fmt.Errorf("sethostname: %w", err)
+	}
+	if err := lockDown(); err != nil {
+		return 0, err
+	}
+
+	// exec.Command looks the program up in our own PATH
+	os.Setenv("PATH", sandboxPath)
+
+	cmd := exec.Command(cfg.Cmd[0], cfg.Cmd[1:]...)
+	cmd.Dir = workspaceDir
+	cmd.Env = append([]string{"PATH=" + sandboxPath, "HOME=/tmp"}, cfg.Env...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return 0, err
+	}
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGUSR1)
+	go func() {
+		for sig := range sigs {
+			if sig == syscall.SIGUSR1 {
+				// a held sandbox is killed by everything but its init,
+				// which keeps the workspace
+				_ = unix.Kill(-1, unix.SIGKILL)
+				continue
+			}
+			_ = cmd.Process.Signal(sig)
+		}
+	}()
+
+	code := reap(cmd.Process.Pid)
+	if cfg.Hold {
+		// nothing may change the workspace while the host collects it
+		_ = unix.Kill(-1, unix.SIGKILL)
+	}
+
+	status, _ := json.Marshal(initStatus{ExitCode: code})
+	if f := os.NewFile(3, "status"); f != nil {
+		_, _ = f.Write(status)
+		f.Close()
+	}
+
+	if cfg.Hold {
+		// the host's output streams end here, not when it releases us
+		os.Stdout.Close()
+		os.Stderr.Close()
+		if f := os.NewFile(4, "release"); f != nil {
+			_, _ = io.Copy(io.Discard, f)
+		}
+	}
+	return code, nil
+}
+
+// reap waits for pid while also reaping anything it orphaned, since the
+// init is PID 1 of the sandbox, and returns pid's exit code.
+func reap(pid int) int {
+	for {
+		var ws unix.WaitStatus
+		wpid, err := unix.Wait4(-1, &ws, 0, nil)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return 127
+		}
+		if wpid != pid {
+			continue
+		}
+		if ws.Signaled() {
+			return 128 + int(ws.Signal())
+		}
+		return ws.ExitStatus()
+	}
+}
+
+// buildRoot assembles a private root on a size limited tmpfs, holding
+// read-only host toolchains, a minimal /etc, a few device nodes, a fresh
+// /proc and the workspace, then pivots into it.
+func buildRoot(cfg initConfig) error {
+	root := cfg.RootDir
+
+	if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+		return fmt.Errorf("make mounts private: %w", err)
+	}
+	if err := unix.Mount("tmpfs", root, "tmpfs", unix.MS_NOSUID|unix.MS_NODEV, "size="+cfg.RootSize+",mode=755"); err != nil {
+		return fmt.Errorf("mount root: %w", err)
+	}
+
+	for _, dir := range hostDirs {
+		fi, err := os.Lstat(dir)
+		if err != nil {
+			continue
+		}
+		target := filepath.Join(root, dir)
+
+		// merged-usr hosts link /bin and friends into /usr
+		if fi.Mode()&fs.ModeSymlink != 0 {
+			link, err := os.Readlink(dir)
+			if err != nil {
+				return err
+			}
+			if err := os.Symlink(link, target); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := os.Mkdir(target, 0755); err != nil {
+			return err
+		}
+		if err := bindReadOnly(dir, target); err != nil {
+			return err
+		}
+	}
+
+	if err := buildEtc(filepath.Join(root, "etc")); err != nil {
+		return err
+	}
+
+	if err := os.Mkdir(filepath.Join(root, "dev"), 0755); err != nil {
+		return err
+	}
+	for _, dev := range hostDevices {
+		target := filepath.Join(root, dev)
+		if err := os.WriteFile(target, nil, 0666); err != nil {
+			return err
+		}
+		if err := unix.Mount(dev, target, "", unix.MS_BIND, ""); err != nil {
+			return fmt.Errorf("bind %s: %w", dev, err)
+		}
+	}
+
+	proc := filepath.Join(root, "proc")
+	if err := os.Mkdir(proc, 0555); err != nil {
+		return err
+	}
+	if err := unix.Mount("proc", proc, "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+		return fmt.Errorf("mount proc: %w", err)
+	}
+
+	tmp := filepath.Join(root, "tmp")
+	if err := os.Mkdir(tmp, 0777); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, 0777|fs.ModeSticky); err != nil {
+		return err
+	}
+
+	workspace := filepath.Join(root, workspaceDir)
+	if err := os.Mkdir(workspace, 0755); err != nil {
+		return err
+	}
+	if cfg.BindSrc {
+		if err := unix.Mount(cfg.SrcDir, workspace, "", unix.MS_BIND|unix.MS_NOSUID|unix.MS_NODEV, ""); err != nil {
+			return fmt.Errorf("bind workspace: %w", err)
+		}
+	} else if err := copyTree(cfg.SrcDir, workspace); err != nil {
+		return fmt.Errorf("populate workspace: %w", err)
+	}
+
+	oldRoot := filepath.Join(root, ".oldroot")
+	if err := os.Mkdir(oldRoot, 0700); err != nil {
+		return err
+	}
+	if err := unix.PivotRoot(root, oldRoot); err != nil {
+		return fmt.Errorf("pivot_root: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return err
+	}
+	if err := unix.Unmount("/.oldroot", unix.MNT_DETACH); err != nil {
+		return fmt.Errorf("detach old root: %w", err)
+	}
+	return os.Remove("/.oldroot")
+}
+
+// buildEtc fills dir with the stubs and the few host files in hostEtc.
+func buildEtc(dir string) error {
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return err
+	}
+	for name, content := range etcStubs {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	for _, pattern := range hostEtc {
+		matches, _ := filepath.Glob(filepath.Join("/etc", pattern))
+		for _, src := range matches {
+			fi, err := os.Lstat(src)
+			if err != nil {
+				continue
+			}
+			target := filepath.Join(dir, filepath.Base(src))
+
+			switch {
+			case fi.Mode()&fs.ModeSymlink != 0:
+				link, err := os.Readlink(src)
+				if err != nil {
+					return err
+				}
+				err = os.Symlink(link, target)
+			case fi.IsDir():
+				if err = os.Mkdir(target, 0755); err == nil {
+					err = bindReadOnly(src, target)
+				}
+			case fi.Mode().IsRegular():
+				if err = os.WriteFile(target, nil, 0644); err == nil {
+					err = bindReadOnly(src, target)
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bindReadOnly bind mounts src on target read-only. Inside a user namespace
+// the remount has to keep the flags the source mount was locked with.
+func bindReadOnly(src, target string) error {
+	if err := unix.Mount(src, target, "", unix.MS_BIND|unix.MS_REC, ""); err != nil {
+		return fmt.Errorf("bind %s: %w", src, err)
+	}
+
+	var st unix.Statfs_t
+	if err := unix.Statfs(target, &st); err != nil {
+		return err
+	}
+	flags := uintptr(unix.MS_BIND | unix.MS_REMOUNT | unix.MS_RDONLY)
+	for stFlag, msFlag := range map[int64]uintptr{
+		unix.ST_NOSUID:     unix.MS_NOSUID,
+		unix.ST_NODEV:      unix.MS_NODEV,
+		unix.ST_NOEXEC:     unix.MS_NOEXEC,
+		unix.ST_NOATIME:    unix.MS_NOATIME,
+		unix.ST_NODIRATIME: unix.MS_NODIRATIME,
+		unix.ST_RELATIME:   unix.MS_RELATIME,
+	} {
+		if int64(st.Flags)&stFlag != 0 {
+			flags |= msFlag
+		}
+	}
+
+	if err := unix.Mount("", target, "", flags, ""); err != nil {
+		return fmt.Errorf("remount %s read-only: %w", src, err)
+	}
+	return nil
+}
+
+// lockDown drops every capability the program could inherit, forbids
+// regaining privileges and installs the seccomp filter.
+func lockDown() error {
+	for c := 0; c <= unix.CAP_LAST_CAP; c++ {
+		if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil && err != unix.EINVAL {
+			return fmt.Errorf("drop capability %d: %w", c, err)
+		}
+	}
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		return fmt.Errorf("no_new_privs: %w", err)
+	}
+	return installSeccomp()
+}
@@ -0,0 +1,417 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"execution-engine/internal/language"
+	"execution-engine/internal/sandbox"
+	"execution-engine/internal/session"
+)
+
+// holdKillTimeout bounds how long Kill waits for a held sandbox to stop
+// its program before killing the whole cgroup.
+const holdKillTimeout = 2 * time.Second
+
+// NewLocalExecutor prepares cgroupRoot (a delegated cgroup v2 directory)
+// for local sandboxes. Sandbox root is the server's own user on the host,
+// so the server must not run as root.
+func NewLocalExecutor(cgroupRoot string) (*LocalExecutor, error) {
+	if os.Geteuid() == 0 {
+		return nil, errors.New("the local sandbox runs code as the server's user; run the server as an unprivileged user, not root")
+	}
+	if cgroupRoot == "" {
+		cgroupRoot = DefaultCgroupRoot
+	}
+	if err := os.MkdirAll(cgroupRoot, 0755); err != nil {
+		return nil, fmt.Errorf("cgroup root: %w", err)
+	}
+	if err := enableControllers(cgroupRoot); err != nil {
+		return nil, fmt.Errorf("cgroup root: %w", err)
+	}
+	restrictToDefaults()
+	return &LocalExecutor{cgroupRoot: cgroupRoot}, nil
+}
+
+// Start implements sandbox.Runtime by re-executing the server binary as the
+// init of a fresh set of namespaces inside the session's cgroup.
+func (l *LocalExecutor) Start(
+	ctx context.Context,
+	s *session.Session,
+) (sandbox.Process, error) {
+
+	if s.Tty {
+		return nil, fmt.Errorf("terminal mode is not supported by the local sandbox")
+	}
+
+	spec, err := language.Resolve(s.Language)
+	if err != nil {
+		return nil, err
+	}
+	if !language.IsDefault(spec) {
+		return nil, fmt.Errorf("%w: %s runs only as the default version in the local sandbox", language.ErrUnavailable, spec.ID())
+	}
+
+	tempDir, err := os.MkdirTemp("", "exec-*")
+	if err != nil {
+		return nil, err
+	}
+
+	srcDir := filepath.Join(tempDir, "src")
+	if err := os.Mkdir(srcDir, 0755); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+	if err := s.Project.Write(srcDir); err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+
+	if len(spec.CompileCmd) > 0 {
+		if err := l.compile(ctx, s, spec, tempDir, srcDir); err != nil {
+			os.RemoveAll(tempDir)
+			return nil, err
+		}
+	}
+
+	// artifacts are read from the size-limited workspace, which only
+	// lives as long as the init holding it
+	proc, err := l.spawn(tempDir, s.ID, initConfig{
+		SrcDir: srcDir,
+		Cmd:    spec.Expand(spec.RunCommand, s.Project),
+		Env:    envList(spec.Env),
+		Hold:   len(s.ArtifactPatterns) > 0,
+	}, runLimits(spec))
+	if err != nil {
+		os.RemoveAll(tempDir)
+		return nil, err
+	}
+
+	// the program's Cleanup removes the whole workspace
+	proc.tempDir = tempDir
+	return proc, nil
+}
+
+// compile builds srcDir in place, in a sandbox of its own with the compile
+// limits.
+func (l *LocalExecutor) compile(
+	ctx context.Context,
+	s *session.Session,
+	spec language.Spec,
+	tempDir, srcDir string,
+) error {
+
+	s.MarkCompiling()
+
+	lim := compileLimits(spec)
+	proc, err := l.spawn(tempDir, s.ID+"-compile", initConfig{
+		SrcDir:  srcDir,
+		Cmd:     spec.Expand(spec.CompileCmd, s.Project),
+		Env:     envList(spec.Env),
+		BindSrc: true,
+	}, lim)
+	if err != nil {
+		return err
+	}
+	defer proc.Cleanup(context.Background())
+
+	// compilers get no input
+	proc.stdin.Close()
+
+	return awaitBuild(ctx, s, proc, lim)
+}
+
+// spawn starts a sandbox init for cfg inside a new cgroup called name,
+// building its root under tempDir.
+func (l *LocalExecutor) spawn(
+	tempDir, name string,
+	cfg initConfig,
+	lim limits,
+) (*localProcess, error) {
+
+	proc := &localProcess{
+		exited:   make(chan struct{}),
+		reported: make(chan struct{}),
+	}
+	fail := func(err error) (*localProcess, error) {
+		_ = proc.Cleanup(context.Background())
+		return nil, err
+	}
+
+	rootDir, err := os.MkdirTemp(tempDir, "root-*")
+	if err != nil {
+		return nil, err
+	}
+	cfg.RootDir = rootDir
+	cfg.RootSize = lim.tmpfs
+
+	proc.cgroup, err = newCgroup(l.cgroupRoot, name, lim)
+	if err != nil {
+		return fail(err)
+	}
+
+	cfgJSON, err := json.Marshal(cfg)
+	if err != nil {
+		return fail(err)
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		return fail(err)
+	}
+
+	cmd := exec.Command(self, sandboxInitArg)
+	cmd.Env = []string{sandboxInitEnv + "=" + string(cfgJSON)}
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Cloneflags: syscall.CLONE_NEWUSER |
+			syscall.CLONE_NEWPID |
+			syscall.CLONE_NEWNS |
+			syscall.CLONE_NEWNET |
+			syscall.CLONE_NEWUTS |
+			syscall.CLONE_NEWIPC,
+		// root inside the sandbox is the server's own user outside it
+		UidMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: os.Getuid(), Size: 1},
+		},
+		GidMappings: []syscall.SysProcIDMap{
+			{ContainerID: 0, HostID: os.Getgid(), Size: 1},
+		},
+		GidMappingsEnableSetgroups: false,
+		UseCgroupFD:                true,
+		CgroupFD:                   int(proc.cgroup.dir.Fd()),
+		Pdeathsig:                  syscall.SIGKILL,
+	}
+
+	// plain pipes instead of cmd.*Pipe so Wait doesn't race the readers
+	var childEnds []*os.File
+	pipe := func() (r, w *os.File) {
+		if err == nil {
+			r, w, err = os.Pipe()
+		}
+		return
+	}
+	stdinR, stdinW := pipe()
+	stdoutR, stdoutW := pipe()
+	stderrR, stderrW := pipe()
+	statusR, statusW := pipe()
+	var releaseR, releaseW *os.File
+	if cfg.Hold {
+		releaseR, releaseW = pipe()
+	}
+	if err != nil {
+		return fail(err)
+	}
+	childEnds = append(childEnds, stdinR, stdoutW, stderrW, statusW)
+	proc.parentEnds = []*os.File{stdinW, stdoutR, stderrR, statusR}
+	proc.stdin, proc.stdout, proc.stderr, proc.status = stdinW, stdoutR, stderrR, statusR
+
+	cmd.Stdin = stdinR
+	cmd.Stdout = stdoutW
+	cmd.Stderr = stderrW
+	cmd.ExtraFiles = []*os.File{statusW} // fd 3 inside the sandbox
+	if cfg.Hold {
+		childEnds = append(childEnds, releaseR)
+		proc.release = releaseW
+		cmd.ExtraFiles = append(cmd.ExtraFiles, releaseR) // fd 4
+	}
+
+	err = cmd.Start()
+	for _, f := range childEnds {
+		f.Close()
+	}
+	if err != nil {
+		return fail(fmt.Errorf("start sandbox: %w", err))
+	}
+	proc.cmd = cmd
+
+	go func() {
+		proc.waitErr = cmd.Wait()
+		close(proc.exited)
+	}()
+	go func() {
+		// the init reports the program's status on fd 3 as the program
+		// exits; if it was killed itself there is nothing to read
+		proc.statusErr = json.NewDecoder(proc.status).Decode(&proc.initStatus)
+		close(proc.reported)
+	}()
+
+	return proc, nil
+}
+
+var _ sandbox.Process = (*localProcess)(nil)
+
+// localProcess is a sandbox init process and the program it supervises.
+type localProcess struct {
+	cmd     *exec.Cmd
+	cgroup  *cgroup
+	tempDir string
+	// release lets a held init exit when closed; nil unless held
+	release *os.File
+
+	stdin      *os.File
+	stdout     *os.File
+	stderr     *os.File
+	status     *os.File
+	parentEnds []*os.File
+
+	exited  chan struct{}
+	waitErr error
+
+	reported   chan struct{}
+	initStatus initStatus
+	statusErr  error
+
+	cleanupOnce sync.Once
+}
+
+func (p *localProcess) ID() string {
+	return fmt.Sprintf("local-%d", p.cmd.Process.Pid)
+}
+
+// WorkspaceDir reaches the sandbox's /workspace through its init, which
+// holds it until Cleanup when the session collects artifacts.
+func (p *localProcess) WorkspaceDir() string {
+	if p.release == nil {
+		return ""
+	}
+	select {
+	case <-p.exited:
+		// the pid may belong to someone else by now
+		return ""
+	default:
+	}
+	return fmt.Sprintf("/proc/%d/root%s", p.cmd.Process.Pid, workspaceDir)
+}
+
+func (p *localProcess) Stdin() io.WriteCloser {
+	return p.stdin
+}
+
+// Stream copies stdout and stderr. They are separate pipes, so ordering
+// between the two streams is only as good as the reader's scheduling.
+func (p *localProcess) Stream(stdout, stderr io.Writer) error {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		_, _ = io.Copy(stderr, p.stderr)
+	}()
+
+	_, err := io.Copy(stdout, p.stdout)
+	wg.Wait()
+	return err
+}
+
+func (p *localProcess) Wait(ctx context.Context) (session.ExitStatus, error) {
+	select {
+	case <-p.reported:
+	case <-ctx.Done():
+		return session.ExitStatus{}, ctx.Err()
+	}
+
+	status := session.ExitStatus{
+		OOMKilled: p.cgroup.oomKilled(),
+	}
+	if p.statusErr == nil {
+		status.ExitCode = p.initStatus.ExitCode
+		return status, nil
+	}
+
+	// the init was killed itself, so its own exit code stands
+	select {
+	case <-p.exited:
+	case <-ctx.Done():
+		return status, ctx.Err()
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case p.waitErr == nil:
+		status.ExitCode = 0
+	case errors.As(p.waitErr, &exitErr):
+		status.ExitCode = exitCode(exitErr.ProcessState)
+	default:
+		return status, p.waitErr
+	}
+	return status, nil
+}
+
+// Kill stops the program. A held init survives it so the workspace can
+// still be collected, unless it doesn't report in time.
+func (p *localProcess) Kill(ctx context.Context) error {
+	if p.release != nil && p.cmd.Process.Signal(syscall.SIGUSR1) == nil {
+		select {
+		case <-p.reported:
+			return nil
+		case <-time.After(holdKillTimeout):
+		}
+	}
+	return p.kill()
+}
+
+// kill takes down the whole sandbox, init included.
+func (p *localProcess) kill() error {
+	if err := p.cgroup.kill(); err == nil {
+		return nil
+	}
+	// older kernels: killing the pid namespace init takes everything with it
+	return p.cmd.Process.Kill()
+}
+
+func (p *localProcess) Resize(ctx context.Context, cols, rows uint) error {
+	return fmt.Errorf("terminal mode is not supported by the local sandbox")
+}
+
+var localSignals = map[string]syscall.Signal{
+	"SIGINT":  syscall.SIGINT,
+	"SIGTERM": syscall.SIGTERM,
+	"SIGKILL": syscall.SIGKILL,
+}
+
+// Signal goes to the sandbox init, which forwards it to the program.
+func (p *localProcess) Signal(ctx context.Context, sig string) error {
+	signum, ok := localSignals[sig]
+	if !ok {
+		return fmt.Errorf("unsupported signal: %s", sig)
+	}
+	return p.cmd.Process.Signal(signum)
+}
+
+// Cleanup ALWAYS removes the cgroup and the workspace.
+func (p *localProcess) Cleanup(ctx context.Context) error {
+	var err error
+	p.cleanupOnce.Do(func() {
+		if p.release != nil {
+			// lets a held init exit
+			p.release.Close()
+		}
+		if p.cmd != nil {
+			_ = p.kill()
+			<-p.exited
+		}
+		for _, f := range p.parentEnds {
+			f.Close()
+		}
+		if p.cgroup != nil {
+			err = p.cgroup.remove()
+		}
+		os.RemoveAll(p.tempDir)
+	})
+	return err
+}
+
+func exitCode(ps *os.ProcessState) int {
+	if ws, ok := ps.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+		return 128 + int(ws.Signal())
+	}
+	return ps.ExitCode()
+}
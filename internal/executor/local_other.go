@@ -0,0 +1,24 @@
+//go:build !linux
+
+package executor
+
+import (
+	"context"
+	"errors"
+
+	"execution-engine/internal/sandbox"
+	"execution-engine/internal/session"
+)
+
+var errLocalUnsupported = errors.New("local sandbox requires linux")
+
+func NewLocalExecutor(cgroupRoot string) (*LocalExecutor, error) {
+	return nil, errLocalUnsupported
+}
+
+func (l *LocalExecutor) Start(ctx context.Context, s *session.Session) (sandbox.Process, error) {
+	return nil, errLocalUnsupported
+}
+
+// MaybeRunSandboxInit is a no-op outside linux.
+func MaybeRunSandboxInit() {}
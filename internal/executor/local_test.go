@@ -0,0 +1,27 @@
+package executor
+
+import (
+	"errors"
+	"testing"
+
+	"execution-engine/internal/language"
+)
+
+func TestRestrictToDefaults(t *testing.T) {
+	restrictToDefaults()
+	t.Cleanup(func() {
+		for _, spec := range language.AllSpecs() {
+			language.SetReady(spec.ID(), true)
+		}
+	})
+
+	for _, spec := range language.AllSpecs() {
+		err := language.Ready(spec.ID())
+		if language.IsDefault(spec) && err != nil {
+			t.Errorf("default %s: %v", spec.ID(), err)
+		}
+		if !language.IsDefault(spec) && !errors.Is(err, language.ErrUnavailable) {
+			t.Errorf("%s: err = %v, want ErrUnavailable", spec.ID(), err)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+
+	"execution-engine/internal/language"
+	"execution-engine/internal/sandbox"
+)
+
+// PoolConfig sizes the warm pool. Each language keeps Min containers
+// created and attached but not started, plus one per session queued for it,
+// up to Max. A container is handed to exactly one session and removed with
+// it; containers never run code twice.
+type PoolConfig struct {
+	Min int
+	Max int
+	// MaxAge replaces idle containers that waited this long, so pooled
+	// containers pick up re-pulled images. Zero keeps them forever.
+	MaxAge time.Duration
+}
+
+// DefaultPoolConfig keeps one container per language ready.
+var DefaultPoolConfig = PoolConfig{
+	Min:    1,
+	Max:    4,
+	MaxAge: 10 * time.Minute,
+}
+
+const (
+	// poolCreateTimeout bounds creating a single pooled container.
+	poolCreateTimeout = 30 * time.Second
+
+	// poolParallelCreates limits how many containers the pool creates at
+	// once, so refilling doesn't starve sessions of the daemon.
+	poolParallelCreates = 2
+
+	poolCheckInterval = 30 * time.Second
+)
+
+var errNotFresh = errors.New("container is not in created state")
+
+var _ sandbox.Warmer = (*DockerExecutor)(nil)
+
+// WithWarmPool keeps pre-created containers ready for sessions.
+func WithWarmPool(cfg PoolConfig) DockerOption {
+	return func(d *DockerExecutor) {
+		if cfg.Max < cfg.Min {
+			cfg.Max = cfg.Min
+		}
+		if cfg.Max > 0 {
+			d.pool = &warmPool{d: d, cfg: cfg}
+		}
+	}
+}
+
+type warmContainer struct {
+	proc    *dockerProcess
+	created time.Time
+}
+
+// languagePool holds one language's idle containers. They are all created
+// for the language's default OCI runtime and single-file run command,
+// without a TTY.
+type languagePool struct {
+	runtime  string
+	cmd      []string
+	idle     []warmContainer // oldest first
+	creating int
+	waiting  int
+	hits     uint64
+	misses   uint64
+}
+
+func (lp *languagePool) target(cfg PoolConfig) int {
+	return min(cfg.Min+lp.waiting, cfg.Max)
+}
+
+type warmPool struct {
+	d   *DockerExecutor
+	cfg PoolConfig
+
+	mu     sync.Mutex
+	langs  map[string]*languagePool
+	closed bool
+
+	kick    chan struct{}
+	creates chan struct{} // semaphore of poolParallelCreates
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// StartPool begins filling the warm pool. Languages are filled once they
+// are ready.
+func (d *DockerExecutor) StartPool() {
+	p := d.pool
+	if p == nil {
+		return
+	}
+
+	p.langs = map[string]*languagePool{}
+	for _, spec := range language.AllSpecs() {
+		p.langs[spec.ID()] = &languagePool{
+			runtime: d.runtimes.For(spec, ""),
+			cmd:     spec.Expand(spec.RunCommand, spec.DefaultProject()),
+		}
+	}
+	p.kick = make(chan struct{}, 1)
+	p.creates = make(chan struct{}, poolParallelCreates)
+	p.stop = make(chan struct{})
+
+	log.Printf("🔥 Warm pool: min=%d max=%d per language", p.cfg.Min, p.cfg.Max)
+
+	p.wg.Add(1)
+	go p.run()
+	p.signal()
+}
+
+// Close stops retrying unavailable images and removes every idle
+// container. Containers already claimed belong to their sessions.
+func (d *DockerExecutor) Close() error {
+	if d.retryCancel != nil {
+		d.retryCancel()
+		<-d.retryDone
+	}
+
+	p := d.pool
+	if p == nil || p.stop == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	p.closed = true
+	var idle []warmContainer
+	for _, lp := range p.langs {
+		idle = append(idle, lp.idle...)
+		lp.idle = nil
+	}
+	p.mu.Unlock()
+
+	close(p.stop)
+	p.wg.Wait()
+
+	for _, wc := range idle {
+		_ = wc.proc.Cleanup(context.Background())
+	}
+	return nil
+}
+
+func (d *DockerExecutor) Want(lang string, waiting int) {
+	p := d.pool
+	if p == nil || p.langs == nil {
+		return
+	}
+
+	p.mu.Lock()
+	lp, ok := p.langs[lang]
+	if ok {
+		lp.waiting = waiting
+	}
+	p.mu.Unlock()
+
+	if ok {
+		p.signal()
+	}
+}
+
+func (d *DockerExecutor) PoolStats() []sandbox.PoolStats {
+	p := d.pool
+	if p == nil || p.langs == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make([]sandbox.PoolStats, 0, len(p.langs))
+	for name, lp := range p.langs {
+		stats = append(stats, sandbox.PoolStats{
+			Language: name,
+			Idle:     len(lp.idle),
+			Target:   lp.target(p.cfg),
+			Hits:     lp.hits,
+			Misses:   lp.misses,
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Language < stats[j].Language
+	})
+	return stats
+}
+
+// claim hands out the newest idle container matching the session, or nil
+// on a miss. Only non-TTY sessions with the default runtime and run command
+// can hit.
+func (p *warmPool) claim(lang, runtime string, cmd []string, tty bool) *dockerProcess {
+	if p == nil || p.langs == nil {
+		return nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	lp, ok := p.langs[lang]
+	if !ok {
+		return nil
+	}
+	if tty || runtime != lp.runtime || !slices.Equal(cmd, lp.cmd) || len(lp.idle) == 0 {
+		lp.misses++
+		return nil
+	}
+
+	last := len(lp.idle) - 1
+	wc := lp.idle[last]
+	lp.idle = lp.idle[:last]
+	lp.hits++
+
+	p.signal()
+	return wc.proc
+}
+
+func (p *warmPool) signal() {
+	select {
+	case p.kick <- struct{}{}:
+	default:
+	}
+}
+
+func (p *warmPool) run() {
+	defer p.wg.Done()
+
+	ticker := time.NewTicker(poolCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-p.kick:
+		case <-ticker.C:
+		}
+		p.expire()
+		p.fill()
+	}
+}
+
+// expire removes idle containers older than MaxAge.
+func (p *warmPool) expire() {
+	if p.cfg.MaxAge <= 0 {
+		return
+	}
+
+	cutoff := time.Now().Add(-p.cfg.MaxAge)
+	var stale []warmContainer
+
+	p.mu.Lock()
+	for _, lp := range p.langs {
+		n := 0
+		for n < len(lp.idle) && lp.idle[n].created.Before(cutoff) {
+			n++
+		}
+		stale = append(stale, lp.idle[:n]...)
+		lp.idle = append([]warmContainer(nil), lp.idle[n:]...)
+	}
+	p.mu.Unlock()
+
+	for _, wc := range stale {
+		_ = wc.proc.Cleanup(context.Background())
+	}
+}
+
+// fill starts creating containers for every language below its target.
+func (p *warmPool) fill() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return
+	}
+
+	for name, lp := range p.langs {
+		if language.Ready(name) != nil {
+			// no image to create from yet
+			continue
+		}
+		need := lp.target(p.cfg) - len(lp.idle) - lp.creating
+		for ; need > 0; need-- {
+			lp.creating++
+			p.wg.Add(1)
+			go p.add(name, lp)
+		}
+	}
+}
+
+func (p *warmPool) add(name string, lp *languagePool) {
+	defer p.wg.Done()
+
+	select {
+	case p.creates <- struct{}{}:
+		defer func() { <-p.creates }()
+	case <-p.stop:
+		p.mu.Lock()
+		lp.creating--
+		p.mu.Unlock()
+		return
+	}
+
+	proc, err := p.create(name, lp.runtime, lp.cmd)
+
+	p.mu.Lock()
+	lp.creating--
+	if err == nil && !p.closed {
+		lp.idle = append(lp.idle, warmContainer{proc: proc, created: time.Now()})
+		proc = nil
+	}
+	p.mu.Unlock()
+
+	if err != nil {
+		// retried on the next check rather than immediately, so a broken
+		// image doesn't turn into a create loop
+		log.Printf("Pool: failed to create %s container: %v", name, err)
+		return
+	}
+	if proc != nil {
+		_ = proc.Cleanup(context.Background())
+	}
+}
+
+func (p *warmPool) create(name, runtime string, cmd []string) (*dockerProcess, error) {
+	spec, err := language.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), poolCreateTimeout)
+	defer cancel()
+
+	proc, err := p.d.create(ctx, runContainer(spec, cmd, runtime, false))
+	if err != nil {
+		return nil, err
+	}
+
+	// a container the daemon lost or that got started somehow must never
+	// reach a session
+	info, err := p.d.cli.ContainerInspect(ctx, proc.containerID)
+	if err != nil || info.State == nil || info.State.Status != container.StateCreated {
+		_ = proc.Cleanup(context.Background())
+		if err == nil {
+			err = errNotFresh
+		}
+		return nil, err
+	}
+
+	return proc, nil
+}
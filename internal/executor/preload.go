@@ -2,27 +2,141 @@ package executor
 
 import (
 	"context"
+	"errors"
 	"log"
+	"time"
 
 	"execution-engine/internal/language"
 )
 
-// PreloadImages pulls all required images before server starts
-func (d *DockerExecutor) PreloadImages(ctx context.Context) error {
-	specs := language.AllSpecs()
+const (
+	imageRetryMinDelay = 5 * time.Second
+	imageRetryMaxDelay = 5 * time.Minute
+	imageRetryTimeout  = 10 * time.Minute // per attempt
+)
 
+// PreloadImages pulls all required images before server starts and
+// verifies the ones pinned by digest. Languages whose image can't be
+// prepared are marked unavailable and their errors returned; the rest are
+// ready to serve. StartImageRetry keeps trying the unavailable ones.
+func (d *DockerExecutor) PreloadImages(ctx context.Context) error {
 	log.Println("🔄 Preloading Docker images...")
 
+	if err := d.prepareImages(ctx, language.AllSpecs()); err != nil {
+		return err
+	}
+
+	log.Println("🎉 All Docker images are ready")
+	return nil
+}
+
+// prepareImages makes specs' images available and marks each spec ready
+// or not.
+func (d *DockerExecutor) prepareImages(ctx context.Context, specs []language.Spec) error {
+	var errs []error
+
+	if d.images.ArchiveDir != "" {
+		names := make([]string, 0, len(specs))
+		for _, spec := range specs {
+			names = append(names, spec.Image)
+		}
+		// the images may still be present or pullable; retries skip the
+		// tarballs already loaded
+		if err := loadArchives(ctx, d.cli, d.images.ArchiveDir, names, &d.archives); err != nil {
+			log.Printf("⚠️  %v", err)
+		}
+	}
+
 	for _, spec := range specs {
-		log.Printf("➡️  checking image: %s (%s)", spec.Image, spec.Name)
+		log.Printf("➡️  checking image: %s (%s)", spec.Image, spec.ID())
 
-		if err := ensureImage(ctx, d.cli, spec.Image); err != nil {
-			return err
+		st, err := d.prepareImage(ctx, spec)
+		if err != nil {
+			language.SetReady(spec.ID(), false)
+			log.Printf("❌ unavailable: %s: %v", spec.ID(), err)
+			errs = append(errs, err)
+			continue
 		}
+		// a ready spec must already resolve to its verified image, not
+		// the mutable tag
+		language.SetImageStatus(spec.ID(), st)
+		language.SetReady(spec.ID(), true)
 
-		log.Printf("✅ ready: %s", spec.Image)
+		if st.Pinned {
+			log.Printf("✅ ready: %s (pinned %s)", spec.Image, st.Digest)
+		} else {
+			log.Printf("✅ ready: %s (%s)", spec.Image, st.Digest)
+		}
 	}
 
-	log.Println("🎉 All Docker images are ready")
-	return nil
+	return errors.Join(errs...)
+}
+
+func (d *DockerExecutor) prepareImage(ctx context.Context, spec language.Spec) (language.ImageStatus, error) {
+	if spec.Digest == "" {
+		if err := d.ensureImage(ctx, spec.Image); err != nil {
+			return language.ImageStatus{}, err
+		}
+		return resolveImage(ctx, d.cli, spec)
+	}
+
+	if st, err := resolveImage(ctx, d.cli, spec); err == nil {
+		return st, nil
+	}
+	// the tag may have moved upstream, so fetch exactly the pinned content;
+	// an image that still doesn't match keeps its language unavailable
+	if err := d.ensureImage(ctx, pinnedRef(spec.Image, spec.Digest)); err != nil {
+		return language.ImageStatus{}, err
+	}
+	return resolveImage(ctx, d.cli, spec)
+}
+
+// StartImageRetry retries the languages PreloadImages left unavailable in
+// the background, backing off between attempts, until all are ready or
+// Close is called.
+func (d *DockerExecutor) StartImageRetry() {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	d.retryCancel = cancel
+	d.retryDone = done
+
+	go func() {
+		defer close(done)
+		d.retryImages(ctx)
+	}()
+}
+
+func (d *DockerExecutor) retryImages(ctx context.Context) {
+	delay := imageRetryMinDelay
+	for {
+		var pending []language.Spec
+		for _, spec := range language.AllSpecs() {
+			if language.Ready(spec.ID()) != nil {
+				pending = append(pending, spec)
+			}
+		}
+		if len(pending) == 0 {
+			log.Println("🎉 All Docker images are ready")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+
+		log.Printf("🔄 Retrying %d unavailable language(s)...", len(pending))
+		attempt, cancel := context.WithTimeout(ctx, imageRetryTimeout)
+		err := d.prepareImages(attempt, pending)
+		cancel()
+
+		if d.pool != nil && d.pool.kick != nil {
+			// warm the languages that just became ready
+			d.pool.signal()
+		}
+		if err != nil {
+			delay = min(2*delay, imageRetryMaxDelay)
+		}
+	}
 }
@@ -0,0 +1,140 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+
+	"execution-engine/internal/sandbox"
+	"execution-engine/internal/session"
+)
+
+var _ sandbox.Process = (*dockerProcess)(nil)
+
+// dockerProcess is a running container attached to a session.
+type dockerProcess struct {
+	cli         *client.Client
+	containerID string
+	tempDir     string
+	tty         bool
+	attach      types.HijackedResponse
+}
+
+func (p *dockerProcess) ID() string {
+	return p.containerID
+}
+
+func (p *dockerProcess) Stdin() io.WriteCloser {
+	return attachStdin{p.attach}
+}
+
+// attachStdin writes to the attach connection. Closing it half-closes the
+// connection, which EOFs the program's stdin while output keeps streaming.
+type attachStdin struct {
+	attach types.HijackedResponse
+}
+
+func (a attachStdin) Write(p []byte) (int, error) {
+	return a.attach.Conn.Write(p)
+}
+
+func (a attachStdin) CloseWrite() error {
+	return a.attach.CloseWrite()
+}
+
+func (a attachStdin) Close() error {
+	return a.attach.CloseWrite()
+}
+
+func (p *dockerProcess) Stream(stdout, stderr io.Writer) error {
+	if p.tty {
+		// a TTY stream is raw, not multiplexed
+		_, err := io.Copy(stdout, p.attach.Reader)
+		return err
+	}
+	_, err := stdcopy.StdCopy(stdout, stderr, p.attach.Reader)
+	return err
+}
+
+func (p *dockerProcess) Wait(ctx context.Context) (session.ExitStatus, error) {
+	waitCh, errCh := p.cli.ContainerWait(
+		ctx,
+		p.containerID,
+		container.WaitConditionNotRunning,
+	)
+
+	select {
+	case res := <-waitCh:
+		if res.Error != nil {
+			return session.ExitStatus{}, fmt.Errorf("container wait: %s", res.Error.Message)
+		}
+		return p.exitStatus(ctx, res), nil
+	case err := <-errCh:
+		return session.ExitStatus{}, fmt.Errorf("container wait: %w", err)
+	}
+}
+
+// exitStatus builds the session exit status from the wait response and
+// the container's final state.
+func (p *dockerProcess) exitStatus(
+	ctx context.Context,
+	res container.WaitResponse,
+) session.ExitStatus {
+	status := session.ExitStatus{
+		ExitCode: int(res.StatusCode),
+	}
+
+	info, err := p.cli.ContainerInspect(ctx, p.containerID)
+	if err != nil {
+		log.Printf("container inspect %s: %v", p.containerID, err)
+	} else if info.State != nil {
+		status.OOMKilled = info.State.OOMKilled
+	}
+
+	return status
+}
+
+func (p *dockerProcess) Kill(ctx context.Context) error {
+	return p.cli.ContainerKill(ctx, p.containerID, "KILL")
+}
+
+func (p *dockerProcess) Resize(ctx context.Context, cols, rows uint) error {
+	return p.cli.ContainerResize(
+		ctx,
+		p.containerID,
+		container.ResizeOptions{
+			Height: rows,
+			Width:  cols,
+		},
+	)
+}
+
+func (p *dockerProcess) Signal(ctx context.Context, sig string) error {
+	return p.cli.ContainerKill(ctx, p.containerID, sig)
+}
+
+// WorkspaceDir is the host directory mounted as the container's /workspace.
+func (p *dockerProcess) WorkspaceDir() string {
+	return p.tempDir
+}
+
+// Cleanup ALWAYS removes the container and the workspace.
+func (p *dockerProcess) Cleanup(ctx context.Context) error {
+	if p.attach.Conn != nil {
+		p.attach.Close()
+	}
+	defer os.RemoveAll(p.tempDir)
+
+	return p.cli.ContainerRemove(
+		ctx,
+		p.containerID,
+		container.RemoveOptions{Force: true},
+	)
+}
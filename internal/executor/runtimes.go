@@ -0,0 +1,92 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"execution-engine/internal/language"
+)
+
+// DefaultOCIRuntime is Docker's stock runtime.
+const DefaultOCIRuntime = "runc"
+
+// OCIRuntimes picks the OCI runtime (runc, runsc, kata, ...) a container
+// runs under. The language spec's Runtime wins over Default, and a tenant
+// mapping can only tighten that: it applies when the runtime would
+// otherwise share the host kernel.
+type OCIRuntimes struct {
+	Default string            // empty means DefaultOCIRuntime
+	Tenants map[string]string // tenant -> runtime
+}
+
+// For returns the runtime for a session of spec run on behalf of tenant.
+func (r OCIRuntimes) For(spec language.Spec, tenant string) string {
+	base := spec.Runtime
+	if base == "" {
+		base = r.Default
+	}
+	if base == "" {
+		base = DefaultOCIRuntime
+	}
+
+	if rt, ok := r.Tenants[tenant]; ok && tenant != "" && kernelSharing[base] {
+		return rt
+	}
+	return base
+}
+
+// kernelSharing runtimes isolate with namespaces alone. Anything else is
+// taken to be a stronger boundary no tenant mapping may trade away.
+var kernelSharing = map[string]bool{
+	"runc":  true,
+	"crun":  true,
+	"youki": true,
+}
+
+// required lists every runtime the configuration can select.
+func (r OCIRuntimes) required() map[string][]string {
+	users := map[string][]string{}
+	def := r.Default
+	if def == "" {
+		def = DefaultOCIRuntime
+	}
+	users[def] = append(users[def], "default")
+
+	for _, spec := range language.AllSpecs() {
+		if spec.Runtime != "" {
+			users[spec.Runtime] = append(users[spec.Runtime], "language "+spec.ID())
+		}
+	}
+	for tenant, rt := range r.Tenants {
+		users[rt] = append(users[rt], "tenant "+tenant)
+	}
+	return users
+}
+
+// ValidateRuntimes checks that the daemon provides every runtime the
+// configuration can select, so a missing runtime fails startup instead of
+// every session that needs it.
+func (d *DockerExecutor) ValidateRuntimes(ctx context.Context) error {
+	info, err := d.cli.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("docker info: %w", err)
+	}
+
+	var missing []string
+	for rt, users := range d.runtimes.required() {
+		if _, ok := info.Runtimes[rt]; ok {
+			log.Printf("✅ OCI runtime available: %s", rt)
+			continue
+		}
+		sort.Strings(users)
+		missing = append(missing, fmt.Sprintf("%s (%s)", rt, strings.Join(users, ", ")))
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("OCI runtimes not available in docker daemon: %s", strings.Join(missing, "; "))
+	}
+	return nil
+}
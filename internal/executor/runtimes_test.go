@@ -0,0 +1,35 @@
+package executor
+
+import (
+	"testing"
+
+	"execution-engine/internal/language"
+)
+
+func TestOCIRuntimesFor(t *testing.T) {
+	r := OCIRuntimes{Tenants: map[string]string{"acme": "runsc", "lax": "runc"}}
+	strict := OCIRuntimes{Default: "kata", Tenants: r.Tenants}
+
+	tests := []struct {
+		name   string
+		r      OCIRuntimes
+		spec   language.Spec
+		tenant string
+		want   string
+	}{
+		{"default", r, language.Spec{}, "", DefaultOCIRuntime},
+		{"tenant tightens", r, language.Spec{}, "acme", "runsc"},
+		{"unknown tenant", r, language.Spec{}, "other", "runc"},
+		{"language runtime", r, language.Spec{Runtime: "kata"}, "", "kata"},
+		{"tenant can't relax language", r, language.Spec{Runtime: "kata"}, "lax", "kata"},
+		{"tenant can't swap language", r, language.Spec{Runtime: "kata"}, "acme", "kata"},
+		{"tenant can't relax default", strict, language.Spec{}, "lax", "kata"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.r.For(tt.spec, tt.tenant); got != tt.want {
+				t.Errorf("For = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+//go:build linux && (amd64 || arm64)
+
+package executor
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+const seccompProbeEnv = "EXECUTOR_SECCOMP_PROBE"
+
+// TestSeccompClone runs the probe below in a child process, since the
+// filter can't be removed from the process that installs it.
+func TestSeccompClone(t *testing.T) {
+	if os.Getenv(seccompProbeEnv) != "" {
+		seccompProbe()
+		return
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestSeccompClone$")
+	cmd.Env = append(os.Environ(), seccompProbeEnv+"=1")
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("probe: %v\n%s", err, out)
+	}
+
+	want := []string{
+		"clone(CLONE_NEWUSER): operation not permitted",
+		"clone(CLONE_NEWNET): operation not permitted",
+		"clone3: function not implemented",
+	}
+	for _, w := range want {
+		if !strings.Contains(string(out), w) {
+			t.Errorf("probe output lacks %q:\n%s", w, out)
+		}
+	}
+}
+
+func seccompProbe() {
+	if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+		fmt.Println("no_new_privs:", err)
+		os.Exit(1)
+	}
+	if err := installSeccomp(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	for _, c := range []struct {
+		name  string
+		flags uintptr
+	}{
+		{"CLONE_NEWUSER", unix.CLONE_NEWUSER},
+		{"CLONE_NEWNET", unix.CLONE_NEWNET},
+	} {
+		pid, _, errno := unix.RawSyscall(unix.SYS_CLONE, c.flags|uintptr(unix.SIGCHLD), 0, 0)
+		if pid == 0 && errno == 0 {
+			// the filter let it through: leave the child no chance to run
+			unix.RawSyscall(unix.SYS_EXIT_GROUP, 0, 0, 0)
+		}
+		fmt.Printf("clone(%s): %v\n", c.name, errno)
+	}
+
+	_, _, errno := unix.RawSyscall(unix.SYS_CLONE3, 0, 0, 0)
+	fmt.Printf("clone3: %v\n", errno)
+}
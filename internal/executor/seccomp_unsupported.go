@@ -0,0 +1,9 @@
+//go:build linux && !amd64 && !arm64
+
+package executor
+
+import "fmt"
+
+func installSeccomp() error {
+	return fmt.Errorf("seccomp: no filter for this architecture")
+}
@@ -5,29 +5,119 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"strings"
 
 	"github.com/docker/docker/api/types/container"
 	"github.com/docker/docker/api/types/mount"
 
 	"execution-engine/internal/language"
+	"execution-engine/internal/sandbox"
 	"execution-engine/internal/session"
 )
 
-const (
-	workspaceDir = "/workspace"
-)
-
-func (d *DockerExecutor) StartSession(
+// Start implements sandbox.Runtime by running the session's code in a
+// fresh, locked-down container, taken from the warm pool when one is ready.
+func (d *DockerExecutor) Start(
 	ctx context.Context,
 	s *session.Session,
-) error {
+) (sandbox.Process, error) {
 
 	spec, err := language.Resolve(s.Language)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	runtime := d.runtimes.For(spec, s.Tenant)
+
+	// compiled languages build in their own container first, unless the
+	// same code was built before
+	var buildDir string
+	if len(spec.CompileCmd) > 0 {
+		buildDir, err = d.build(ctx, s, spec, runtime)
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(buildDir)
+	}
+
+	cmd := spec.Expand(spec.RunCommand, s.Project)
+	proc := d.pool.claim(spec.ID(), runtime, cmd, s.Tty)
+	if proc == nil {
+		proc, err = d.create(ctx, runContainer(spec, cmd, runtime, s.Tty))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if buildDir != "" {
+		err = copyTree(buildDir, proc.tempDir)
+	} else {
+		err = s.Project.Write(proc.tempDir)
+	}
+	if err != nil {
+		_ = proc.Cleanup(context.Background())
+		return nil, err
+	}
+
+	if err := d.cli.ContainerStart(ctx, proc.containerID, container.StartOptions{}); err != nil {
+		_ = proc.Cleanup(context.Background())
+		return nil, fmt.Errorf("container start: %w", err)
 	}
 
+	return proc, nil
+}
+
+// containerSpec describes one sandbox container.
+type containerSpec struct {
+	image   string
+	cmd     []string
+	env     []string
+	limits  limits
+	runtime string
+	tty     bool
+	stdin   bool
+}
+
+// runContainer is the container that runs spec's program with cmd.
+func runContainer(spec language.Spec, cmd []string, runtime string, tty bool) containerSpec {
+	return containerSpec{
+		image:   imageRef(spec),
+		cmd:     cmd,
+		env:     envList(spec.Env),
+		limits:  runLimits(spec),
+		runtime: runtime,
+		tty:     tty,
+		stdin:   true,
+	}
+}
+
+// config is the container's configuration, short of its host settings.
+func (cs containerSpec) config() *container.Config {
+	return &container.Config{
+		Image:       cs.image,
+		Cmd:         cs.cmd,
+		Env:         cs.env,
+		WorkingDir:  workspaceDir,
+		Tty:         cs.tty,
+		OpenStdin:   cs.stdin,
+		AttachStdin: cs.stdin,
+		// the daemon only closes the program's stdin when the attach
+		// half-closes if StdinOnce is set; otherwise it hangs up our
+		// stdout and stderr instead. A TTY has no EOF to forward, ^D is
+		// sent as input.
+		StdinOnce:       cs.stdin && !cs.tty,
+		AttachStdout:    true,
+		AttachStderr:    true,
+		NetworkDisabled: true,
+	}
+}
+
+// create makes a locked-down container with an empty workspace and
+// attaches to it, without starting it.
+func (d *DockerExecutor) create(
+	ctx context.Context,
+	cs containerSpec,
+) (*dockerProcess, error) {
+
+	var err error
 	var tempDir string
 	var mountConfig mount.Mount
 
@@ -37,13 +127,13 @@ func (d *DockerExecutor) StartSession(
 		volName := os.Getenv("DOCKER_VOLUME_NAME")
 
 		if baseDir == "" || volName == "" {
-			return fmt.Errorf("missing env vars for docker volume mode")
+			return nil, fmt.Errorf("missing env vars for docker volume mode")
 		}
 
 		// Create temp dir inside the shared volume mount (e.g., /app/workspace/exec-123)
 		tempDir, err = os.MkdirTemp(baseDir, "exec-*")
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Extract the relative directory name (e.g., exec-123)
@@ -63,7 +153,7 @@ func (d *DockerExecutor) StartSession(
 		// --- Running Locally (Host) ---
 		tempDir, err = os.MkdirTemp("", "exec-*")
 		if err != nil {
-			return err
+			return nil, err
 		}
 
 		// Bind mount the temp dir directly to /workspace
@@ -75,49 +165,24 @@ func (d *DockerExecutor) StartSession(
 		}
 	}
 
-	codePath := filepath.Join(tempDir, spec.FileName)
-	if err := os.WriteFile(codePath, []byte(s.Code), 0644); err != nil {
-		return err
-	}
-
-	cmd := spec.RunCommand
-
-	if len(spec.CompileCmd) > 0 {
-		cmd = []string{
-			"sh",
-			"-c",
-			fmt.Sprintf(
-				"%s && exec %s",
-				strings.Join(spec.CompileCmd, " "),
-				strings.Join(spec.RunCommand, " "),
-			),
-		}
-	}
-
 	createResp, err := d.cli.ContainerCreate(
 		ctx,
-		&container.Config{
-			Image:           spec.Image,
-			Cmd:             cmd,
-			WorkingDir:      workspaceDir,
-			OpenStdin:       true,
-			AttachStdin:     true,
-			StdinOnce:       false,
-			AttachStdout:    true,
-			AttachStderr:    true,
-			NetworkDisabled: true,
-		},
+		cs.config(),
 		&container.HostConfig{
 			Resources: container.Resources{
-				Memory:    200 * 1024 * 1024,
-				NanoCPUs:  500_000_000,
-				PidsLimit: ptr(int64(32)),
+				Memory:    cs.limits.memory,
+				NanoCPUs:  cs.limits.nanoCPUs,
+				PidsLimit: ptr(cs.limits.pids),
 			},
+			Runtime: cs.runtime,
+			// run an init as PID 1 so signals sent by clients reach the
+			// program with their default behaviour
+			Init:           ptr(true),
 			ReadonlyRootfs: true,
 			CapDrop:        []string{"ALL"},
 			SecurityOpt:    []string{"no-new-privileges"},
 			Tmpfs: map[string]string{
-				"/tmp": "rw,size=32m,noexec,nosuid",
+				"/tmp": "rw,size=" + cs.limits.tmpfs + ",noexec,nosuid",
 			},
 			Mounts: []mount.Mount{
 				mountConfig, // 🔥 Dynamic mount config
@@ -126,7 +191,15 @@ func (d *DockerExecutor) StartSession(
 		nil, nil, "",
 	)
 	if err != nil {
-		return fmt.Errorf("container create: %w", err)
+		os.RemoveAll(tempDir)
+		return nil, fmt.Errorf("container create: %w", err)
+	}
+
+	proc := &dockerProcess{
+		cli:         d.cli,
+		containerID: createResp.ID,
+		tempDir:     tempDir,
+		tty:         cs.tty,
 	}
 
 	attach, err := d.cli.ContainerAttach(
@@ -134,31 +207,16 @@ func (d *DockerExecutor) StartSession(
 		createResp.ID,
 		container.AttachOptions{
 			Stream: true,
-			Stdin:  true,
+			Stdin:  cs.stdin,
 			Stdout: true,
 			Stderr: true,
 		},
 	)
 	if err != nil {
-		return fmt.Errorf("container attach: %w", err)
-	}
-
-	if err := d.cli.ContainerStart(ctx, createResp.ID, container.StartOptions{}); err != nil {
-		return err
+		_ = proc.Cleanup(context.Background())
+		return nil, fmt.Errorf("container attach: %w", err)
 	}
+	proc.attach = attach
 
-	sessCtx, cancel := context.WithCancel(context.Background())
-
-	// Update the EXISTING session with runtime details
-	s.SetRuntime(
-		createResp.ID,
-		attach.Conn,
-		attach.Reader,
-		sessCtx,
-		cancel,
-	)
-
-	go d.watchSession(s, tempDir)
-
-	return nil
+	return proc, nil
 }
@@ -0,0 +1,14 @@
+package language
+
+func init() {
+	Register(Spec{
+		Name:     "bash",
+		Version:  "5.2",
+		Image:    "bash:5.2",
+		FileName: "main.sh",
+		RunCommand: []string{
+			"bash",
+			"/workspace/{entry}",
+		},
+	})
+}
@@ -0,0 +1,23 @@
+package language
+
+func init() {
+	Register(Spec{
+		Name:       "c",
+		Version:    "c17",
+		Image:      "gcc:14",
+		FileName:   "main.c",
+		SourceExts: []string{".c"},
+		CompileCmd: []string{
+			"gcc",
+			"-std=c17",
+			"{sources}",
+			"-O2",
+			"-o",
+			"/workspace/a.out",
+			"-lm",
+		},
+		RunCommand: []string{
+			"/workspace/a.out",
+		},
+	})
+}
@@ -0,0 +1,55 @@
+package language
+
+import (
+	"path"
+	"strings"
+
+	"execution-engine/internal/project"
+)
+
+// Placeholders RunCommand and CompileCmd may contain, filled in per
+// submission by Expand.
+const (
+	// Entry is the entry file's path relative to the workspace.
+	Entry = "{entry}"
+	// Module is the entry path without its extension and with / as .,
+	// e.g. com.acme.App for com/acme/App.java.
+	Module = "{module}"
+	// Sources, as a whole argument, expands to one argument per source
+	// file (see Spec.SourceExts).
+	Sources = "{sources}"
+)
+
+// Expand fills the placeholders in cmd for p.
+func (s Spec) Expand(cmd []string, p project.Project) []string {
+	module := strings.TrimSuffix(p.Entry, path.Ext(p.Entry))
+	module = strings.ReplaceAll(module, "/", ".")
+
+	out := make([]string, 0, len(cmd))
+	for _, arg := range cmd {
+		if arg == Sources {
+			out = append(out, p.Sources(s.sourceExts())...)
+			continue
+		}
+		arg = strings.ReplaceAll(arg, Entry, p.Entry)
+		arg = strings.ReplaceAll(arg, Module, module)
+		out = append(out, arg)
+	}
+	return out
+}
+
+// DefaultProject is a single file under the spec's usual name, what a
+// request with only code becomes.
+func (s Spec) DefaultProject() project.Project {
+	return project.Project{
+		Files: []project.File{{Path: s.FileName}},
+		Entry: s.FileName,
+	}
+}
+
+func (s Spec) sourceExts() []string {
+	if len(s.SourceExts) > 0 {
+		return s.SourceExts
+	}
+	return []string{path.Ext(s.FileName)}
+}
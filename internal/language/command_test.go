@@ -0,0 +1,44 @@
+package language
+
+import (
+	"slices"
+	"testing"
+
+	"execution-engine/internal/project"
+)
+
+func TestExpand(t *testing.T) {
+	java, err := Resolve("java")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := project.Project{
+		Entry: "com/acme/App.java",
+		Files: []project.File{
+			{Path: "README.md"},
+			{Path: "com/acme/App.java"},
+			{Path: "com/acme/Util.java"},
+		},
+	}
+
+	compile := java.Expand(java.CompileCmd, p)
+	if want := []string{"javac", "-d", "/workspace", "com/acme/App.java", "com/acme/Util.java"}; !slices.Equal(compile, want) {
+		t.Errorf("compile = %q, want %q", compile, want)
+	}
+	run := java.Expand(java.RunCommand, p)
+	if want := []string{"java", "-cp", "/workspace", "com.acme.App"}; !slices.Equal(run, want) {
+		t.Errorf("run = %q, want %q", run, want)
+	}
+}
+
+func TestExpandDefaultProject(t *testing.T) {
+	py, err := Resolve("python")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run := py.Expand(py.RunCommand, py.DefaultProject())
+	if want := []string{"python", "-u", "/workspace/main.py"}; !slices.Equal(run, want) {
+		t.Errorf("run = %q, want %q", run, want)
+	}
+}
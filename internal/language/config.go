@@ -0,0 +1,226 @@
+package language
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+)
+
+// Config is a language registry file. JSON is valid YAML, so both work:
+//
+//	languages:
+//	  - name: php
+//	    version: "8.3"
+//	    image: php:8.3-cli-alpine
+//	    digest: sha256:...   # optional, pins the image
+//	    fileName: main.php
+//	    run: [php, "/workspace/{entry}"]
+//	    env: {TZ: UTC}
+//	    limits: {memory: 256m}
+type Config struct {
+	Languages []ConfigSpec `yaml:"languages"`
+}
+
+// ConfigSpec is one language in a Config.
+type ConfigSpec struct {
+	Name       string            `yaml:"name"`
+	Version    string            `yaml:"version"`
+	Default    bool              `yaml:"default"`
+	Image      string            `yaml:"image"`
+	Digest     string            `yaml:"digest"`
+	FileName   string            `yaml:"fileName"`
+	SourceExts []string          `yaml:"sourceExts"`
+	Compile    []string          `yaml:"compile"`
+	Run        []string          `yaml:"run"`
+	Env        map[string]string `yaml:"env"`
+	Runtime    string            `yaml:"runtime"`
+	Limits     ConfigLimits      `yaml:"limits"`
+	// Override must be set to replace a built-in spec with the same name
+	// and version, so a typo can't silently shadow one.
+	Override bool `yaml:"override"`
+}
+
+// ConfigLimits are sizes like "512m" or "1g" (binary units) and durations
+// like "45s".
+type ConfigLimits struct {
+	Memory         string        `yaml:"memory"`
+	CompileMemory  string        `yaml:"compileMemory"`
+	CompileTimeout time.Duration `yaml:"compileTimeout"`
+}
+
+var (
+	validName   = regexp.MustCompile(`^[a-z0-9][a-z0-9_+.-]*$`)
+	validDigest = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+)
+
+// LoadFile reads a Config and registers its languages alongside the
+// built-ins. Nothing is registered unless the whole file is valid.
+func LoadFile(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return err
+	}
+
+	var cfg Config
+	if err := yaml.UnmarshalWithOptions(data, &cfg, yaml.Strict()); err != nil {
+		return fmt.Errorf("%s: %s", name, yaml.FormatError(err, false, true))
+	}
+
+	specs, err := cfg.Specs()
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	for _, spec := range specs {
+		Register(spec)
+	}
+	return nil
+}
+
+// Specs validates every language in c against the registry and converts
+// them, reporting all problems at once.
+func (c Config) Specs() ([]Spec, error) {
+	var (
+		specs []Spec
+		errs  []error
+		seen  = map[string]bool{}
+		// language name -> ID of the spec marked default
+		defaultOf = map[string]string{}
+	)
+
+	for i, cs := range c.Languages {
+		spec, err := cs.spec()
+		id := spec.ID()
+		switch _, builtin := registry[id]; {
+		case err != nil:
+		case seen[id]:
+			err = errors.New("defined more than once")
+		case builtin && !cs.Override:
+			err = errors.New("already built in; set override: true to replace it")
+		case cs.Version == "" && len(Versions(cs.Name)) > 0:
+			err = fmt.Errorf("set a version; built in are %s", strings.Join(Versions(cs.Name), ", "))
+		case cs.Default && defaultOf[cs.Name] != "":
+			err = fmt.Errorf("default: true is already set on %s", defaultOf[cs.Name])
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("language %d (%s): %w", i+1, cs.Name, err))
+			continue
+		}
+		seen[id] = true
+		if cs.Default {
+			defaultOf[cs.Name] = id
+		}
+		specs = append(specs, spec)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return specs, nil
+}
+
+func (cs ConfigSpec) spec() (Spec, error) {
+	var missing []string
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"name", cs.Name != ""},
+		{"image", cs.Image != ""},
+		{"fileName", cs.FileName != ""},
+		{"run", len(cs.Run) > 0},
+	} {
+		if !f.set {
+			missing = append(missing, f.name)
+		}
+	}
+	if len(missing) > 0 {
+		return Spec{}, fmt.Errorf("missing %s", strings.Join(missing, ", "))
+	}
+
+	if !validName.MatchString(cs.Name) {
+		return Spec{}, fmt.Errorf("name must match %s", validName)
+	}
+	if cs.Version != "" && !validName.MatchString(cs.Version) {
+		return Spec{}, fmt.Errorf("version must match %s", validName)
+	}
+	if cs.Digest != "" && !validDigest.MatchString(cs.Digest) {
+		return Spec{}, fmt.Errorf("digest must match %s", validDigest)
+	}
+	if clean := path.Clean(cs.FileName); clean != cs.FileName || path.IsAbs(clean) || strings.HasPrefix(clean, "..") {
+		return Spec{}, fmt.Errorf("fileName %q must be a clean relative path", cs.FileName)
+	}
+	for _, ext := range cs.SourceExts {
+		if !strings.HasPrefix(ext, ".") {
+			return Spec{}, fmt.Errorf("source extension %q must start with a dot", ext)
+		}
+	}
+	for k := range cs.Env {
+		if k == "" || strings.ContainsAny(k, "=\x00") {
+			return Spec{}, fmt.Errorf("bad env name %q", k)
+		}
+	}
+
+	memory, err := parseBytes(cs.Limits.Memory)
+	if err != nil {
+		return Spec{}, fmt.Errorf("limits.memory: %w", err)
+	}
+	compileMemory, err := parseBytes(cs.Limits.CompileMemory)
+	if err != nil {
+		return Spec{}, fmt.Errorf("limits.compileMemory: %w", err)
+	}
+	if cs.Limits.CompileTimeout < 0 {
+		return Spec{}, errors.New("limits.compileTimeout must not be negative")
+	}
+
+	return Spec{
+		Name:           cs.Name,
+		Version:        cs.Version,
+		Default:        cs.Default,
+		Image:          cs.Image,
+		Digest:         cs.Digest,
+		FileName:       cs.FileName,
+		SourceExts:     cs.SourceExts,
+		CompileCmd:     cs.Compile,
+		RunCommand:     cs.Run,
+		Env:            cs.Env,
+		Runtime:        cs.Runtime,
+		Memory:         memory,
+		CompileMemory:  compileMemory,
+		CompileTimeout: cs.Limits.CompileTimeout,
+	}, nil
+}
+
+// parseBytes reads sizes like 512m, 1g or 1048576. Empty is zero.
+func parseBytes(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	num := strings.ToLower(s)
+	num = strings.TrimSuffix(strings.TrimSuffix(num, "b"), "i")
+
+	shift := 0
+	switch {
+	case strings.HasSuffix(num, "k"):
+		shift = 10
+	case strings.HasSuffix(num, "m"):
+		shift = 20
+	case strings.HasSuffix(num, "g"):
+		shift = 30
+	}
+	if shift > 0 {
+		num = num[:len(num)-1]
+	}
+
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n <= 0 || n > (1<<62)>>shift {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n << shift, nil
+}
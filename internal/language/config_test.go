@@ -0,0 +1,135 @@
+package language
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// keepRegistry restores the registry once the test is done, so loading a
+// file can't leak into other tests or a rerun.
+func keepRegistry(t *testing.T) {
+	t.Helper()
+	savedRegistry, savedDefaults := maps.Clone(registry), maps.Clone(defaults)
+	t.Cleanup(func() {
+		registry, defaults = savedRegistry, savedDefaults
+	})
+}
+
+func TestLoadFile(t *testing.T) {
+	keepRegistry(t)
+	name := filepath.Join(t.TempDir(), "languages.yaml")
+	err := os.WriteFile(name, []byte(`
+languages:
+  - name: test-ruby
+    image: ruby:3.3-alpine
+    fileName: main.rb
+    run: [ruby, "/workspace/{entry}"]
+    env:
+      RUBYOPT: -W0
+    limits:
+      memory: 256m
+      compileTimeout: 45s
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := LoadFile(name); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	spec, err := Resolve("test-ruby")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spec.Memory != 256<<20 || spec.CompileTimeout != 45*time.Second || spec.Env["RUBYOPT"] != "-W0" {
+		t.Errorf("spec = %+v", spec)
+	}
+}
+
+func TestLoadFileJSON(t *testing.T) {
+	keepRegistry(t)
+	name := filepath.Join(t.TempDir(), "languages.json")
+	err := os.WriteFile(name, []byte(`{"languages": [
+		{"name": "test-bash", "image": "bash:5", "fileName": "main.sh", "run": ["bash", "/workspace/{entry}"]}
+	]}`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadFile(name); err != nil {
+		t.Fatalf("LoadFile: %v", err)
+	}
+	if _, err := Resolve("test-bash"); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestConfigErrors(t *testing.T) {
+	ok := ConfigSpec{Name: "test-ok", Image: "img", FileName: "main.x", Run: []string{"x"}}
+	with := func(f func(*ConfigSpec)) ConfigSpec {
+		cs := ok
+		f(&cs)
+		return cs
+	}
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{"missing fields", Config{Languages: []ConfigSpec{{Name: "test-x"}}}, "missing image, fileName, run"},
+		{"duplicate", Config{Languages: []ConfigSpec{ok, ok}}, "language 2 (test-ok): defined more than once"},
+		{"builtin", Config{Languages: []ConfigSpec{with(func(cs *ConfigSpec) { cs.Name, cs.Version = "python", "3.12" })}}, "set override: true"},
+		{"unversioned", Config{Languages: []ConfigSpec{with(func(cs *ConfigSpec) { cs.Name = "python" })}}, "set a version; built in are python@3.11, python@3.12, python@3.8"},
+		{"bad name", Config{Languages: []ConfigSpec{with(func(cs *ConfigSpec) { cs.Name = "Py thon" })}}, "name must match"},
+		{"escaping file", Config{Languages: []ConfigSpec{with(func(cs *ConfigSpec) { cs.FileName = "../x" })}}, "clean relative path"},
+		{"bad size", Config{Languages: []ConfigSpec{with(func(cs *ConfigSpec) { cs.Limits.Memory = "lots" })}}, "limits.memory"},
+		{"two defaults", Config{Languages: []ConfigSpec{
+			with(func(cs *ConfigSpec) { cs.Version, cs.Default = "1", true }),
+			with(func(cs *ConfigSpec) { cs.Version, cs.Default = "2", true }),
+		}}, "language 2 (test-ok): default: true is already set on test-ok@1"},
+		{"short digest", Config{Languages: []ConfigSpec{with(func(cs *ConfigSpec) { cs.Digest = "sha256:abc" })}}, "digest must match"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.cfg.Specs()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+
+	override := with(func(cs *ConfigSpec) {
+		cs.Name = "python"
+		cs.Version = "3.12"
+		cs.Override = true
+	})
+	if _, err := (Config{Languages: []ConfigSpec{override}}).Specs(); err != nil {
+		t.Errorf("override: %v", err)
+	}
+}
+
+func TestParseBytes(t *testing.T) {
+	tests := map[string]int64{
+		"":       0,
+		"1024":   1024,
+		"512m":   512 << 20,
+		"512MiB": 512 << 20,
+		"2g":     2 << 30,
+		"64KB":   64 << 10,
+	}
+	for in, want := range tests {
+		if got, err := parseBytes(in); err != nil || got != want {
+			t.Errorf("parseBytes(%q) = %d, %v, want %d", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"-1", "1t", "m", "1.5g"} {
+		if _, err := parseBytes(in); err == nil {
+			t.Errorf("parseBytes(%q) should fail", in)
+		}
+	}
+}
@@ -1,13 +1,24 @@
 package language
 
 func init() {
-	Register(Spec{
+	Register(cpp("c++17"))
+	Register(cpp("c++20"))
+}
+
+// cpp compiles with the given -std, on a pinned GCC so the standard
+// library doesn't change under a course.
+func cpp(std string) Spec {
+	return Spec{
 		Name:     "cpp",
-		Image:    "gcc:latest",
+		Version:  std,
+		Image:    "gcc:14",
 		FileName: "main.cpp",
+		// headers are found relative to the files including them
+		SourceExts: []string{".cpp", ".cc", ".cxx"},
 		CompileCmd: []string{
 			"g++",
-			"/workspace/main.cpp",
+			"-std=" + std,
+			"{sources}",
 			"-O2",
 			"-o",
 			"/workspace/a.out",
@@ -15,5 +26,5 @@ func init() {
 		RunCommand: []string{
 			"/workspace/a.out",
 		},
-	})
+	}
 }
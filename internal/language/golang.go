@@ -0,0 +1,34 @@
+package language
+
+import "time"
+
+func init() {
+	Register(Spec{
+		Name:     "go",
+		Version:  "1.23",
+		Image:    "golang:1.23-alpine",
+		FileName: "main.go",
+		// the root filesystem is read-only, so caches live in /tmp; fewer
+		// parallel compiles keep the toolchain within the pids limit
+		Env: map[string]string{
+			"HOME":        "/tmp",
+			"GOCACHE":     "/tmp/go-build",
+			"GOPATH":      "/tmp/go",
+			"GOFLAGS":     "-p=2",
+			"GOMAXPROCS":  "2",
+			"GOTELEMETRY": "off",
+			"CGO_ENABLED": "0",
+		},
+		CompileCmd: []string{
+			"go",
+			"build",
+			"-o",
+			"/workspace/main",
+			"{sources}",
+		},
+		CompileTimeout: 60 * time.Second,
+		RunCommand: []string{
+			"/workspace/main",
+		},
+	})
+}
@@ -1,21 +1,29 @@
 package language
 
 func init() {
-	Register(Spec{
+	Register(java("21", "eclipse-temurin:21-jdk-alpine"))
+	Register(java("17", "eclipse-temurin:17-jdk-alpine"))
+}
+
+func java(version, image string) Spec {
+	return Spec{
 		Name:     "java",
-		Image:    "eclipse-temurin:21-jdk-alpine",
+		Version:  version,
+		Image:    image,
 		FileName: "Main.java",
 
 		CompileCmd: []string{
 			"javac",
-			"/workspace/Main.java",
+			"-d",
+			"/workspace",
+			"{sources}",
 		},
 
 		RunCommand: []string{
 			"java",
 			"-cp",
 			"/workspace",
-			"Main",
+			"{module}",
 		},
-	})
+	}
 }
@@ -3,11 +3,12 @@ package language
 func init() {
 	Register(Spec{
 		Name:     "javascript",
+		Version:  "20",
 		Image:    "node:20-alpine",
 		FileName: "main.js",
 		RunCommand: []string{
 			"node",
-			"/workspace/main.js",
+			"/workspace/{entry}",
 		},
 	})
 }
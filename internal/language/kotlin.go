@@ -0,0 +1,35 @@
+package language
+
+import "time"
+
+func init() {
+	// there is no official kotlinc image; build this one from
+	// images/kotlin before starting the server
+	Register(Spec{
+		Name:     "kotlin",
+		Version:  "2.1",
+		Image:    "execution-engine/kotlin:2.1",
+		FileName: "Main.kt",
+		// kotlinc's script caps the compiler heap at 256m unless told
+		// otherwise; HOME must be writable
+		Env: map[string]string{
+			"HOME":      "/tmp",
+			"JAVA_OPTS": "-Xmx1g -Xss2m",
+		},
+		CompileCmd: []string{
+			"kotlinc",
+			"{sources}",
+			"-include-runtime",
+			"-d",
+			"/workspace/main.jar",
+		},
+		CompileMemory:  1536 << 20,
+		CompileTimeout: 90 * time.Second,
+		Memory:         256 << 20,
+		RunCommand: []string{
+			"java",
+			"-jar",
+			"/workspace/main.jar",
+		},
+	})
+}
@@ -1,14 +1,21 @@
 package language
 
 func init() {
-	Register(Spec{
+	Register(python("3.11", "python:3.11-alpine"))
+	Register(python("3.8", "python:3.8-alpine"))
+	Register(python("3.12", "python:3.12-alpine"))
+}
+
+func python(version, image string) Spec {
+	return Spec{
 		Name:     "python",
-		Image:    "python:3.11-alpine",
+		Version:  version,
+		Image:    image,
 		FileName: "main.py",
 		RunCommand: []string{
 			"python",
 			"-u",
-			"/workspace/main.py",
+			"/workspace/{entry}",
 		},
-	})
+	}
 }
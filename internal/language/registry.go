@@ -1,25 +1,140 @@
 package language
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
 
-var registry = map[string]Spec{}
+var (
+	// ErrUnsupported is returned when no spec is registered for a language.
+	ErrUnsupported = errors.New("unsupported language")
+	// ErrUnavailable is returned for a registered spec that can't run
+	// sessions yet, such as one whose image is still loading.
+	ErrUnavailable = errors.New("language unavailable")
+)
 
+var (
+	registry = map[string]Spec{}   // by ID
+	defaults = map[string]string{} // language name -> ID of its default spec
+)
+
+// Register adds spec. The first spec of a language, or one marked Default,
+// becomes what the bare language name resolves to.
 func Register(spec Spec) {
-	registry[spec.Name] = spec
+	id := spec.ID()
+	registry[id] = spec
+	if spec.Default || defaults[spec.Name] == "" {
+		defaults[spec.Name] = id
+	}
 }
 
+// Resolve finds a spec by ID ("python@3.12") or by language name
+// ("python"), which picks the language's default version.
 func Resolve(name string) (Spec, error) {
-	spec, ok := registry[name]
+	id := name
+	if def, ok := defaults[name]; ok {
+		id = def
+	}
+	spec, ok := registry[id]
 	if !ok {
-		return Spec{}, fmt.Errorf("unsupported language: %s", name)
+		lang, _, _ := strings.Cut(name, "@")
+		if versions := Versions(lang); len(versions) > 0 {
+			return Spec{}, fmt.Errorf("%w: %s (available: %s)", ErrUnsupported, name, strings.Join(versions, ", "))
+		}
+		return Spec{}, fmt.Errorf("%w: %s", ErrUnsupported, name)
 	}
 	return spec, nil
 }
 
+// IsDefault reports whether spec is what its bare language name resolves
+// to.
+func IsDefault(spec Spec) bool {
+	return defaults[spec.Name] == spec.ID()
+}
+
+// Versions lists the IDs registered for a language.
+func Versions(name string) []string {
+	var ids []string
+	for id, spec := range registry {
+		if spec.Name == name {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// AllSpecs returns every registered spec, ordered by ID.
 func AllSpecs() []Spec {
 	specs := make([]Spec, 0, len(registry))
 	for _, spec := range registry {
 		specs = append(specs, spec)
 	}
+	sort.Slice(specs, func(i, j int) bool {
+		return specs[i].ID() < specs[j].ID()
+	})
 	return specs
 }
+
+// ImageStatus is what the executor found for a spec's image.
+type ImageStatus struct {
+	ID     string // local image ID
+	Digest string // repo digest, or the image ID for images without one
+	Pinned bool   // matched Spec.Digest
+}
+
+var (
+	statusMu sync.RWMutex
+	images   = map[string]ImageStatus{} // by spec ID
+	// specs are ready unless marked otherwise, so backends without images
+	// to load never need to
+	unready = map[string]string{} // by spec ID, why it can't run
+)
+
+// SetImageStatus records the image resolved for the spec with id.
+func SetImageStatus(id string, st ImageStatus) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	images[id] = st
+}
+
+// ImageStatusOf returns the image resolved for the spec with id, if any.
+func ImageStatusOf(id string) (ImageStatus, bool) {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	st, ok := images[id]
+	return st, ok
+}
+
+// SetReady records whether the spec with id can run sessions.
+func SetReady(id string, ready bool) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	if ready {
+		delete(unready, id)
+	} else {
+		unready[id] = "is still loading"
+	}
+}
+
+// SetUnavailable marks the spec with id unable to run sessions on this
+// server at all; reason completes "<id> ...".
+func SetUnavailable(id, reason string) {
+	statusMu.Lock()
+	defer statusMu.Unlock()
+	unready[id] = reason
+}
+
+// Ready returns an error wrapping ErrUnavailable unless the spec with id
+// can run sessions.
+func Ready(id string) error {
+	statusMu.RLock()
+	defer statusMu.RUnlock()
+	if reason, ok := unready[id]; ok {
+		return fmt.Errorf("%w: %s %s", ErrUnavailable, id, reason)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package language
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestResolveVersions(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"python", "python@3.11"},
+		{"python@3.12", "python@3.12"},
+		{"cpp", "cpp@c++17"},
+		{"cpp@c++20", "cpp@c++20"},
+		{"java@17", "java@17"},
+	}
+	for _, tt := range tests {
+		spec, err := Resolve(tt.name)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", tt.name, err)
+			continue
+		}
+		if spec.ID() != tt.want {
+			t.Errorf("Resolve(%q) = %s, want %s", tt.name, spec.ID(), tt.want)
+		}
+	}
+
+	spec, _ := Resolve("cpp@c++20")
+	if !strings.Contains(strings.Join(spec.CompileCmd, " "), "-std=c++20") {
+		t.Errorf("cpp@c++20 compiles with %q", spec.CompileCmd)
+	}
+	if IsDefault(spec) {
+		t.Error("cpp@c++20 should not be the default")
+	}
+}
+
+func TestResolveUnknownVersion(t *testing.T) {
+	_, err := Resolve("python@2.7")
+	if !errors.Is(err, ErrUnsupported) {
+		t.Fatalf("err = %v, want ErrUnsupported", err)
+	}
+	if !strings.Contains(err.Error(), "python@3.12") {
+		t.Errorf("error %q should list the available versions", err)
+	}
+}
+
+func TestReady(t *testing.T) {
+	if err := Ready("go@1.23"); err != nil {
+		t.Fatalf("specs start ready, got %v", err)
+	}
+
+	SetReady("go@1.23", false)
+	if err := Ready("go@1.23"); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("err = %v, want ErrUnavailable", err)
+	}
+
+	SetReady("go@1.23", true)
+	if err := Ready("go@1.23"); err != nil {
+		t.Errorf("err = %v after SetReady(true)", err)
+	}
+}
+
+func TestBuiltinSpecs(t *testing.T) {
+	for _, name := range []string{"python", "javascript", "java", "cpp", "c", "go", "rust", "ruby", "typescript", "bash", "kotlin"} {
+		spec, err := Resolve(name)
+		if err != nil {
+			t.Errorf("Resolve(%q): %v", name, err)
+			continue
+		}
+		if spec.Image == "" || spec.FileName == "" || len(spec.RunCommand) == 0 {
+			t.Errorf("%s: incomplete spec %+v", spec.ID(), spec)
+		}
+
+		// a single-file submission must reach the compiler, or the
+		// interpreter when there is none
+		cmd := spec.RunCommand
+		if len(spec.CompileCmd) > 0 {
+			cmd = spec.CompileCmd
+		}
+		args := strings.Join(spec.Expand(cmd, spec.DefaultProject()), " ")
+		if !strings.Contains(args, spec.FileName) {
+			t.Errorf("%s: %q never mentions %s", spec.ID(), args, spec.FileName)
+		}
+	}
+}
+
+func TestSetUnavailable(t *testing.T) {
+	SetUnavailable("go@1.23", "is not served here")
+	t.Cleanup(func() { SetReady("go@1.23", true) })
+
+	err := Ready("go@1.23")
+	if !errors.Is(err, ErrUnavailable) || !strings.Contains(err.Error(), "go@1.23 is not served here") {
+		t.Errorf("err = %v", err)
+	}
+}
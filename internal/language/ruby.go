@@ -0,0 +1,14 @@
+package language
+
+func init() {
+	Register(Spec{
+		Name:     "ruby",
+		Version:  "3.3",
+		Image:    "ruby:3.3-alpine",
+		FileName: "main.rb",
+		RunCommand: []string{
+			"ruby",
+			"/workspace/{entry}",
+		},
+	})
+}
@@ -0,0 +1,27 @@
+package language
+
+import "time"
+
+func init() {
+	Register(Spec{
+		Name:     "rust",
+		Version:  "1.83",
+		Image:    "rust:1.83-alpine",
+		FileName: "main.rs",
+		// rustc finds the other files through the entry's mod declarations
+		CompileCmd: []string{
+			"rustc",
+			"--edition",
+			"2021",
+			"-O",
+			"-o",
+			"/workspace/main",
+			"/workspace/{entry}",
+		},
+		CompileMemory:  1 << 30,
+		CompileTimeout: 60 * time.Second,
+		RunCommand: []string{
+			"/workspace/main",
+		},
+	})
+}
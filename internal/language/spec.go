@@ -1,9 +1,69 @@
 package language
 
+import "time"
+
+// Resource defaults for specs that don't set their own.
+const (
+	DefaultMemory         = 200 << 20
+	DefaultCompileMemory  = 512 << 20
+	DefaultCompileTimeout = 30 * time.Second
+)
+
 type Spec struct {
-	Name       string
-	Image      string
-	FileName   string
+	Name string
+	// Version tells variants of a language apart, e.g. "3.12" or "c++20".
+	// Empty for languages with a single spec.
+	Version string
+	// Default makes this the spec the bare language name resolves to.
+	// Otherwise the first registered version is.
+	Default bool
+
+	Image string
+	// Digest pins Image to exact content: the "sha256:..." repo digest
+	// or image ID it must resolve to. Containers are then created from
+	// the verified image ID rather than the tag.
+	Digest string
+	// FileName is the entry file of single-file submissions.
+	FileName string
+	// RunCommand and CompileCmd run inside the workspace and may use the
+	// {entry}, {module} and {sources} placeholders.
 	RunCommand []string
 	CompileCmd []string // optional
+	// SourceExts are the extensions {sources} picks up. Defaults to
+	// FileName's.
+	SourceExts []string
+	// CompileTimeout and CompileMemory (bytes) override the default
+	// compile limits when set.
+	CompileTimeout time.Duration
+	CompileMemory  int64
+	// Env is set for the compiler and the program.
+	Env map[string]string
+	// Memory (bytes) overrides DefaultMemory for the program when set.
+	Memory int64
+	// Runtime is the OCI runtime containers for this language run under,
+	// e.g. "runsc" for gVisor. Empty uses the executor default.
+	Runtime string
+}
+
+// ID names the spec in requests: "python@3.12", or just the name for
+// unversioned languages.
+func (s Spec) ID() string {
+	if s.Version == "" {
+		return s.Name
+	}
+	return s.Name + "@" + s.Version
+}
+
+// Effective returns s with unset limits filled in from the defaults.
+func (s Spec) Effective() Spec {
+	if s.Memory == 0 {
+		s.Memory = DefaultMemory
+	}
+	if s.CompileMemory == 0 {
+		s.CompileMemory = DefaultCompileMemory
+	}
+	if s.CompileTimeout == 0 {
+		s.CompileTimeout = DefaultCompileTimeout
+	}
+	return s
 }
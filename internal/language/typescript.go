@@ -0,0 +1,18 @@
+package language
+
+func init() {
+	// Node strips the types itself, so there is no compile step; local
+	// imports need their .ts extension
+	Register(Spec{
+		Name:     "typescript",
+		Version:  "node24",
+		Image:    "node:24-alpine",
+		FileName: "main.ts",
+		RunCommand: []string{
+			"node",
+			"--experimental-transform-types",
+			"--no-warnings",
+			"/workspace/{entry}",
+		},
+	})
+}
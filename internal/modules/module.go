@@ -1,16 +1,51 @@
 package modules
 
+// File is one source file of a multi-file submission. Path is relative to
+// the workspace, using forward slashes.
+type File struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Encoding is "base64" for binary content, empty for plain text.
+	Encoding string `json:"encoding,omitempty"`
+}
+
 type ExecuteRequest struct {
-	Language    string
-	Code        string
-	TimeLimitMs int64
-	Inputs      []string
+	Language string `json:"language"`
+	Code     string `json:"code"`
+	// Files replaces Code with a whole project. Entry picks the file that
+	// is run; it defaults to the language's usual file name (main.py,
+	// Main.java, ...).
+	Files []File `json:"files,omitempty"`
+	Entry string `json:"entry,omitempty"`
+	// Artifacts are globs (relative to the workspace, ** for any depth)
+	// of files the program writes that should be kept after it exits.
+	Artifacts   []string `json:"artifacts,omitempty"`
+	TimeLimitMs int64    `json:"timeLimitMs"`
+	Inputs      []string `json:"inputs"`
+	// Tty allocates a pseudo-terminal so the program sees an interactive
+	// terminal; stdout and stderr arrive as one raw stream.
+	Tty bool `json:"tty"`
+	// Tenant identifies who the code runs for; the executor may map it to
+	// a stricter OCI runtime. It comes from the caller's credentials, never
+	// from the request body.
+	Tenant string `json:"-"`
 }
 
 type ExecuteResult struct {
-	ExitCode   int
-	Stdout     string
-	Stderr     string
-	DurationMs int64
-	TimedOut   bool
+	// SessionID names the finished session, e.g. to fetch its artifacts.
+	// With SESSION_REMOVE_ON_FETCH the session is dropped once the result
+	// is returned, unless the request declared artifacts.
+	SessionID  string `json:"sessionId"`
+	ExitCode   int    `json:"exitCode"`
+	Stdout     string `json:"stdout"`
+	Stderr     string `json:"stderr"`
+	Output     string `json:"output"` // stdout and stderr interleaved as written
+	DurationMs int64  `json:"durationMs"`
+	TimedOut   bool   `json:"timedOut"`
+	OOMKilled  bool   `json:"oomKilled"`
+	Reason     string `json:"reason"`
+
+	// compiler output, empty for interpreted languages
+	CompileStdout string `json:"compileStdout"`
+	CompileStderr string `json:"compileStderr"`
 }
@@ -0,0 +1,187 @@
+// Package project validates submitted source files and lays them out in a
+// sandbox workspace.
+package project
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"execution-engine/internal/modules"
+)
+
+// ErrInvalid is returned for submissions that can't be laid out safely.
+var ErrInvalid = errors.New("invalid project")
+
+const (
+	// MaxFiles is the most files one submission may have.
+	MaxFiles = 64
+	// MaxBytes bounds the decoded size of all files together.
+	MaxBytes = 1 << 20
+)
+
+type File struct {
+	Path string // clean, relative, slash separated
+	Data []byte
+}
+
+// Project is the source a session runs: its files, sorted by path, and the
+// one the language's commands treat as the program.
+type Project struct {
+	Files []File
+	Entry string
+}
+
+// FromRequest validates req's source. A request with just Code is a single
+// file called defaultFile.
+func FromRequest(req modules.ExecuteRequest, defaultFile string) (Project, error) {
+	files := req.Files
+	if len(files) == 0 {
+		files = []modules.File{{Path: defaultFile, Content: req.Code}}
+	} else if req.Code != "" {
+		return Project{}, invalid("send either code or files, not both")
+	}
+	if len(files) > MaxFiles {
+		return Project{}, invalid("more than %d files", MaxFiles)
+	}
+
+	p := Project{Entry: defaultFile}
+	if req.Entry != "" {
+		entry, err := cleanPath(req.Entry)
+		if err != nil {
+			return Project{}, err
+		}
+		p.Entry = entry
+	}
+
+	seen := map[string]bool{}
+	var total int
+	for _, f := range files {
+		name, err := cleanPath(f.Path)
+		if err != nil {
+			return Project{}, err
+		}
+		if seen[name] {
+			return Project{}, invalid("duplicate file %q", name)
+		}
+		seen[name] = true
+
+		data, err := decode(f)
+		if err != nil {
+			return Project{}, err
+		}
+		total += len(data)
+		if total > MaxBytes {
+			return Project{}, invalid("files exceed %d bytes", MaxBytes)
+		}
+
+		p.Files = append(p.Files, File{Path: name, Data: data})
+	}
+
+	// a file can't also be a directory holding other files
+	for name := range seen {
+		for dir := path.Dir(name); dir != "."; dir = path.Dir(dir) {
+			if seen[dir] {
+				return Project{}, invalid("%q is both a file and a directory", dir)
+			}
+		}
+	}
+	if !seen[p.Entry] {
+		return Project{}, invalid("entry %q is not one of the files", p.Entry)
+	}
+
+	sort.Slice(p.Files, func(i, j int) bool {
+		return p.Files[i].Path < p.Files[j].Path
+	})
+	return p, nil
+}
+
+func invalid(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalid, fmt.Sprintf(format, args...))
+}
+
+// cleanPath accepts relative paths that stay inside the workspace. Paths
+// are passed to compilers as arguments, so no part may look like an option.
+func cleanPath(p string) (string, error) {
+	if p == "" || strings.ContainsAny(p, "\\\x00") || path.IsAbs(p) {
+		return "", invalid("bad file path %q", p)
+	}
+	clean := path.Clean(p)
+	if clean == "." || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", invalid("file path %q leaves the workspace", p)
+	}
+	for _, part := range strings.Split(clean, "/") {
+		if strings.HasPrefix(part, "-") {
+			return "", invalid("file path %q has a part starting with '-'", p)
+		}
+	}
+	return clean, nil
+}
+
+func decode(f modules.File) ([]byte, error) {
+	switch f.Encoding {
+	case "":
+		return []byte(f.Content), nil
+	case "base64":
+		data, err := base64.StdEncoding.DecodeString(f.Content)
+		if err != nil {
+			return nil, invalid("%s: %v", f.Path, err)
+		}
+		return data, nil
+	default:
+		return nil, invalid("%s: unknown encoding %q", f.Path, f.Encoding)
+	}
+}
+
+// Write lays the files out under dir.
+func (p Project) Write(dir string) error {
+	for _, f := range p.Files {
+		dst := filepath.Join(dir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(dst, f.Data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Sources lists the files with one of exts, in path order.
+func (p Project) Sources(exts []string) []string {
+	var out []string
+	for _, f := range p.Files {
+		for _, ext := range exts {
+			if path.Ext(f.Path) == ext {
+				out = append(out, f.Path)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Fingerprint identifies the project's content, entry included.
+func (p Project) Fingerprint() string {
+	h := sha256.New()
+	field := func(b []byte) {
+		var n [8]byte
+		binary.BigEndian.PutUint64(n[:], uint64(len(b)))
+		h.Write(n[:])
+		h.Write(b)
+	}
+	field([]byte(p.Entry))
+	for _, f := range p.Files {
+		field([]byte(f.Path))
+		field(f.Data)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,128 @@
+package project
+
+import (
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"execution-engine/internal/modules"
+)
+
+func TestFromRequest(t *testing.T) {
+	files := func(paths ...string) []modules.File {
+		var out []modules.File
+		for _, p := range paths {
+			out = append(out, modules.File{Path: p, Content: "x"})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name      string
+		req       modules.ExecuteRequest
+		wantEntry string
+		wantErr   bool
+	}{
+		{"code only", modules.ExecuteRequest{Code: "print(1)"}, "main.py", false},
+		{"default entry", modules.ExecuteRequest{Files: files("main.py", "util.py")}, "main.py", false},
+		{"custom entry", modules.ExecuteRequest{Files: files("app/cli.py", "app/util.py"), Entry: "app/cli.py"}, "app/cli.py", false},
+		{"entry is cleaned", modules.ExecuteRequest{Files: files("app/cli.py"), Entry: "./app/cli.py"}, "app/cli.py", false},
+		{"missing entry", modules.ExecuteRequest{Files: files("util.py")}, "", true},
+		{"code and files", modules.ExecuteRequest{Code: "x", Files: files("main.py")}, "", true},
+		{"parent dir", modules.ExecuteRequest{Files: files("main.py", "../etc/passwd")}, "", true},
+		{"hidden parent dir", modules.ExecuteRequest{Files: files("main.py", "a/../../b")}, "", true},
+		{"absolute", modules.ExecuteRequest{Files: files("main.py", "/etc/passwd")}, "", true},
+		{"backslash", modules.ExecuteRequest{Files: files("main.py", `..\x`)}, "", true},
+		{"option-like file", modules.ExecuteRequest{Files: files("main.c", "-fplugin=x.so")}, "", true},
+		{"option-like dir", modules.ExecuteRequest{Files: files("main.c", "-o/x.c")}, "", true},
+		{"option-like entry", modules.ExecuteRequest{Files: files("-main.py"), Entry: "-main.py"}, "", true},
+		{"duplicate", modules.ExecuteRequest{Files: files("main.py", "./main.py")}, "", true},
+		{"file and dir", modules.ExecuteRequest{Files: files("main.py", "lib", "lib/x.py")}, "", true},
+		{"bad encoding", modules.ExecuteRequest{Files: []modules.File{{Path: "main.py", Encoding: "hex"}}}, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := FromRequest(tt.req, "main.py")
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("err = %v, want ErrInvalid", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FromRequest: %v", err)
+			}
+			if p.Entry != tt.wantEntry {
+				t.Errorf("entry = %q, want %q", p.Entry, tt.wantEntry)
+			}
+		})
+	}
+}
+
+func TestLimits(t *testing.T) {
+	many := make([]modules.File, MaxFiles+1)
+	for i := range many {
+		many[i] = modules.File{Path: strings.Repeat("a", i+1) + ".py"}
+	}
+	many[0].Path = "main.py"
+	if _, err := FromRequest(modules.ExecuteRequest{Files: many}, "main.py"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("too many files: err = %v", err)
+	}
+
+	big := strings.Repeat("x", MaxBytes/2+1)
+	req := modules.ExecuteRequest{Files: []modules.File{
+		{Path: "main.py", Content: big},
+		{Path: "data.txt", Content: big},
+	}}
+	if _, err := FromRequest(req, "main.py"); !errors.Is(err, ErrInvalid) {
+		t.Errorf("too large: err = %v", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	blob := []byte{0, 1, 2, 0xff}
+	p, err := FromRequest(modules.ExecuteRequest{
+		Entry: "src/main.py",
+		Files: []modules.File{
+			{Path: "src/main.py", Content: "import lib"},
+			{Path: "src/lib/__init__.py"},
+			{Path: "data.bin", Content: base64.StdEncoding.EncodeToString(blob), Encoding: "base64"},
+		},
+	}, "main.py")
+	if err != nil {
+		t.Fatalf("FromRequest: %v", err)
+	}
+
+	dir := t.TempDir()
+	if err := p.Write(dir); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "data.bin"))
+	if err != nil || string(got) != string(blob) {
+		t.Errorf("data.bin = %v, %v", got, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "src", "lib", "__init__.py")); err != nil {
+		t.Error(err)
+	}
+
+	if got := p.Sources([]string{".py"}); strings.Join(got, " ") != "src/lib/__init__.py src/main.py" {
+		t.Errorf("Sources = %v", got)
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Project{Entry: "a.py", Files: []File{{Path: "a.py", Data: []byte("x")}, {Path: "b.py"}}}
+	b := Project{Entry: "b.py", Files: a.Files}
+	c := Project{Entry: "a.py", Files: []File{{Path: "a.py", Data: []byte("xb.py")}}}
+
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Error("entry not part of the fingerprint")
+	}
+	if a.Fingerprint() == c.Fingerprint() {
+		t.Error("file boundaries not part of the fingerprint")
+	}
+}
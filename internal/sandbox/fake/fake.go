@@ -0,0 +1,401 @@
+// Package fake is an in-process sandbox.Runtime that simulates containers
+// from scripts, so the engine, sessions and API can be tested without a
+// Docker daemon.
+package fake
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"execution-engine/internal/sandbox"
+	"execution-engine/internal/session"
+)
+
+// Step is one action of a scripted program, run in order.
+type Step struct {
+	// Delay pauses before the step runs.
+	Delay time.Duration
+
+	Stdout string
+	Stderr string
+
+	// ReadLine blocks until a line of stdin arrives; ReadAll blocks until
+	// stdin is closed. With Echo the input read is written to stdout.
+	ReadLine bool
+	ReadAll  bool
+	Echo     bool
+}
+
+// Script describes how a fake program behaves.
+type Script struct {
+	Steps []Step
+
+	ExitCode  int
+	OOMKilled bool
+
+	// A compile phase runs before the program when any of these is set.
+	// CompileFailed fails the build with ExitCode.
+	CompileStdout string
+	CompileStderr string
+	CompileFailed bool
+
+	// Files are written to the process's workspace directory, as if the
+	// program had created them.
+	Files map[string]string
+
+	// Hang keeps the program running after its steps until it is killed.
+	Hang bool
+
+	// KillDelay simulates a sandbox that takes a while to kill.
+	KillDelay time.Duration
+
+	// StartDelay and StartErr simulate a slow or failing container start.
+	StartDelay time.Duration
+	StartErr   error
+}
+
+// Runtime starts fake processes. Script picks the behaviour per session;
+// when nil every session exits 0 without output.
+type Runtime struct {
+	Script func(s *session.Session) Script
+
+	mu         sync.Mutex
+	procs      []*Process
+	running    int
+	maxRunning int
+}
+
+var _ sandbox.Runtime = (*Runtime)(nil)
+
+// New returns a runtime that runs script for every session.
+func New(script Script) *Runtime {
+	return &Runtime{
+		Script: func(*session.Session) Script { return script },
+	}
+}
+
+func (r *Runtime) Start(ctx context.Context, s *session.Session) (sandbox.Process, error) {
+	var script Script
+	if r.Script != nil {
+		script = r.Script(s)
+	}
+
+	if script.CompileStdout != "" || script.CompileStderr != "" || script.CompileFailed {
+		s.MarkCompiling()
+		if script.CompileStdout != "" {
+			_, _ = io.WriteString(s.CompileStdoutWriter(), script.CompileStdout)
+		}
+		if script.CompileStderr != "" {
+			_, _ = io.WriteString(s.CompileStderrWriter(), script.CompileStderr)
+		}
+		if script.CompileFailed {
+			return nil, &sandbox.CompileError{ExitCode: script.ExitCode}
+		}
+	}
+
+	if script.StartDelay > 0 {
+		select {
+		case <-time.After(script.StartDelay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if script.StartErr != nil {
+		return nil, script.StartErr
+	}
+
+	var workspace string
+	if len(script.Files) > 0 {
+		dir, err := writeFiles(script.Files)
+		if err != nil {
+			return nil, err
+		}
+		workspace = dir
+	}
+
+	stdinR, stdinW := io.Pipe()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := &Process{
+		runtime: r,
+		id:      fmt.Sprintf("fake-%d", len(r.procs)+1),
+		script:  script,
+		tty:     s.Tty,
+		dir:     workspace,
+		stdinR:  stdinR,
+		stdinW:  stdinW,
+		in:      bufio.NewReader(stdinR),
+		exited:  make(chan struct{}),
+		killed:  make(chan struct{}),
+	}
+	r.procs = append(r.procs, p)
+	r.running++
+	if r.running > r.maxRunning {
+		r.maxRunning = r.running
+	}
+
+	return p, nil
+}
+
+// Processes returns every process started so far, in start order.
+func (r *Runtime) Processes() []*Process {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*Process(nil), r.procs...)
+}
+
+// Running is the number of processes started and not yet cleaned up.
+func (r *Runtime) Running() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.running
+}
+
+// MaxRunning is the highest Running value seen so far.
+func (r *Runtime) MaxRunning() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.maxRunning
+}
+
+// Process is a scripted program. It runs while the engine streams its output.
+type Process struct {
+	runtime *Runtime
+	id      string
+	script  Script
+	tty     bool
+	dir     string
+
+	stdinR *io.PipeReader
+	stdinW *io.PipeWriter
+	in     *bufio.Reader
+
+	mu       sync.Mutex
+	status   session.ExitStatus
+	exited   chan struct{}
+	exitOnce sync.Once
+	killed   chan struct{}
+	killOnce sync.Once
+	signals  []string
+	cols     uint
+	rows     uint
+	cleaned  bool
+}
+
+var (
+	_ sandbox.Process   = (*Process)(nil)
+	_ sandbox.Workspace = (*Process)(nil)
+)
+
+func writeFiles(files map[string]string) (string, error) {
+	dir, err := os.MkdirTemp("", "fake-workspace-*")
+	if err != nil {
+		return "", err
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			os.RemoveAll(dir)
+			return "", err
+		}
+	}
+	return dir, nil
+}
+
+func (p *Process) ID() string {
+	return p.id
+}
+
+func (p *Process) WorkspaceDir() string {
+	return p.dir
+}
+
+func (p *Process) Stdin() io.WriteCloser {
+	return stdin{p.stdinW}
+}
+
+// stdin supports CloseWrite like a hijacked Docker connection does.
+type stdin struct {
+	*io.PipeWriter
+}
+
+func (s stdin) CloseWrite() error {
+	return s.PipeWriter.Close()
+}
+
+func (p *Process) Stream(stdout, stderr io.Writer) error {
+	if p.tty {
+		stderr = stdout
+	}
+
+	for _, step := range p.script.Steps {
+		if step.Delay > 0 {
+			select {
+			case <-time.After(step.Delay):
+			case <-p.killed:
+				return nil
+			}
+		}
+
+		if step.Stdout != "" {
+			if _, err := io.WriteString(stdout, step.Stdout); err != nil {
+				return err
+			}
+		}
+		if step.Stderr != "" {
+			if _, err := io.WriteString(stderr, step.Stderr); err != nil {
+				return err
+			}
+		}
+
+		var input string
+		var err error
+		switch {
+		case step.ReadLine:
+			input, err = p.in.ReadString('\n')
+		case step.ReadAll:
+			var b []byte
+			b, err = io.ReadAll(p.in)
+			input = string(b)
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			// stdin torn down by Kill
+			return nil
+		}
+		if step.Echo && input != "" {
+			if _, err := io.WriteString(stdout, input); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.script.Hang {
+		<-p.killed
+		return nil
+	}
+
+	p.exit(session.ExitStatus{
+		ExitCode:  p.script.ExitCode,
+		OOMKilled: p.script.OOMKilled,
+	})
+	return nil
+}
+
+func (p *Process) exit(status session.ExitStatus) {
+	p.exitOnce.Do(func() {
+		p.mu.Lock()
+		p.status = status
+		p.mu.Unlock()
+		close(p.exited)
+	})
+}
+
+func (p *Process) Wait(ctx context.Context) (session.ExitStatus, error) {
+	select {
+	case <-p.exited:
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		return p.status, nil
+	case <-ctx.Done():
+		return session.ExitStatus{}, ctx.Err()
+	}
+}
+
+func (p *Process) Kill(ctx context.Context) error {
+	if p.script.KillDelay > 0 {
+		time.Sleep(p.script.KillDelay)
+	}
+	p.kill(137)
+	return nil
+}
+
+// kill stops the script wherever it is and exits with code.
+func (p *Process) kill(code int) {
+	p.killOnce.Do(func() {
+		close(p.killed)
+		p.stdinR.CloseWithError(io.ErrClosedPipe)
+		p.exit(session.ExitStatus{ExitCode: code})
+	})
+}
+
+// signalExitCodes maps signals to the exit code of a program killed by them.
+var signalExitCodes = map[string]int{
+	"SIGINT":  130,
+	"SIGKILL": 137,
+	"SIGTERM": 143,
+}
+
+// Signal records sig and ends the program as if it had no handler for it.
+func (p *Process) Signal(ctx context.Context, sig string) error {
+	p.mu.Lock()
+	p.signals = append(p.signals, sig)
+	p.mu.Unlock()
+
+	code, ok := signalExitCodes[strings.ToUpper(sig)]
+	if !ok {
+		return fmt.Errorf("fake: unsupported signal %s", sig)
+	}
+	p.kill(code)
+	return nil
+}
+
+// Signals returns the signals delivered so far.
+func (p *Process) Signals() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.signals...)
+}
+
+func (p *Process) Resize(ctx context.Context, cols, rows uint) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cols, p.rows = cols, rows
+	return nil
+}
+
+// Size is the last terminal size set through Resize.
+func (p *Process) Size() (cols, rows uint) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cols, p.rows
+}
+
+func (p *Process) Cleanup(ctx context.Context) error {
+	p.mu.Lock()
+	if p.cleaned {
+		p.mu.Unlock()
+		return errors.New("fake: cleanup called twice")
+	}
+	p.cleaned = true
+	p.mu.Unlock()
+
+	if p.dir != "" {
+		os.RemoveAll(p.dir)
+	}
+
+	p.runtime.mu.Lock()
+	p.runtime.running--
+	p.runtime.mu.Unlock()
+	return nil
+}
+
+// CleanedUp reports whether Cleanup has been called.
+func (p *Process) CleanedUp() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.cleaned
+}
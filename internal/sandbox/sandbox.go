@@ -0,0 +1,92 @@
+// Package sandbox defines the contract between the engine and the backends
+// that actually run untrusted code, so the engine never depends on Docker.
+package sandbox
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"execution-engine/internal/session"
+)
+
+// Runtime starts the sandboxed process that backs a session.
+type Runtime interface {
+	// Start prepares and launches the program for s. The program must be
+	// running with its streams attached when Start returns.
+	//
+	// Compiled languages are built first in a separate sandbox with the
+	// compile limits: Start marks s COMPILING, writes the compiler output
+	// to s's compile writers and returns a *CompileError if the build fails.
+	Start(ctx context.Context, s *session.Session) (Process, error)
+}
+
+// CompileError is returned by Runtime.Start when the program didn't build.
+type CompileError struct {
+	ExitCode  int
+	OOMKilled bool
+	TimedOut  bool
+}
+
+func (e *CompileError) Error() string {
+	switch {
+	case e.TimedOut:
+		return "compile timed out"
+	case e.OOMKilled:
+		return "compiler ran out of memory"
+	}
+	return fmt.Sprintf("compile failed with exit code %d", e.ExitCode)
+}
+
+// Process is a single sandboxed program started by a Runtime.
+type Process interface {
+	session.Controller
+
+	// ID identifies the process in its backend, e.g. a container ID.
+	ID() string
+
+	// Stdin is the program's standard input. If it implements
+	// CloseWrite, closing the write side delivers EOF.
+	Stdin() io.WriteCloser
+
+	// Stream copies the program's output into stdout and stderr until the
+	// program closes them. TTY processes write everything to stdout.
+	Stream(stdout, stderr io.Writer) error
+
+	// Wait blocks until the program exits on its own.
+	Wait(ctx context.Context) (session.ExitStatus, error)
+
+	// Kill stops the program immediately.
+	Kill(ctx context.Context) error
+
+	// Cleanup releases everything the process holds. It is called exactly
+	// once, after the program has exited or been killed.
+	Cleanup(ctx context.Context) error
+}
+
+// Workspace is implemented by processes whose workspace is a host
+// directory that outlives the program until Cleanup, so the engine can
+// collect the files it wrote.
+type Workspace interface {
+	// WorkspaceDir is the directory, or "" if this process has none.
+	WorkspaceDir() string
+}
+
+// Warmer is implemented by runtimes that prepare sandboxes ahead of Start.
+type Warmer interface {
+	// Want reports how many sessions of language are queued waiting for a
+	// slot, so the runtime can have sandboxes ready when slots free up.
+	Want(language string, waiting int)
+
+	// PoolStats reports the state of the warm pool per language.
+	PoolStats() []PoolStats
+}
+
+// PoolStats describes one language's warm pool.
+type PoolStats struct {
+	Language string `json:"language"`
+	Idle     int    `json:"idle"`   // ready to be claimed
+	Target   int    `json:"target"` // idle count the pool is filling towards
+	Hits     uint64 `json:"hits"`   // starts served from the pool
+	Misses   uint64 `json:"misses"` // starts that created a container
+}
@@ -0,0 +1,105 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// controlTimeout bounds each call into the Controller, which may be a
+// round-trip to a slow container daemon.
+const controlTimeout = 10 * time.Second
+
+// Controller acts on the process backing a session. The executor provides
+// one once the container is running.
+type Controller interface {
+	Resize(ctx context.Context, cols, rows uint) error
+	Signal(ctx context.Context, sig string) error
+}
+
+// allowedSignals are the signals clients may send to a running program.
+var allowedSignals = map[string]bool{
+	"SIGINT":  true,
+	"SIGTERM": true,
+	"SIGKILL": true,
+}
+
+// SetController hands the session a way to act on its running process and
+// applies any terminal size requested before it started.
+func (s *Session) SetController(c Controller) {
+	s.mu.Lock()
+	s.controller = c
+	sized := s.Tty && s.termCols > 0 && s.termRows > 0
+	s.mu.Unlock()
+
+	if sized {
+		_ = s.applySize(c)
+	}
+}
+
+// Resize changes the terminal size of a TTY session. Sizes requested
+// before the program starts are applied as soon as it does.
+func (s *Session) Resize(cols, rows uint) error {
+	if cols == 0 || rows == 0 {
+		return fmt.Errorf("invalid terminal size %dx%d", cols, rows)
+	}
+
+	s.mu.Lock()
+	if !s.Tty {
+		defer s.mu.Unlock()
+		return fmt.Errorf("session has no terminal")
+	}
+	s.termCols, s.termRows = cols, rows
+	c := s.controller
+	s.mu.Unlock()
+
+	if c == nil {
+		return nil
+	}
+	return s.applySize(c)
+}
+
+// applySize sends the latest requested terminal size to c. Calls run one
+// at a time and outside mu, so the last size requested is the one that
+// sticks and a slow daemon doesn't hold up the session.
+func (s *Session) applySize(c Controller) error {
+	s.resizeMu.Lock()
+	defer s.resizeMu.Unlock()
+
+	s.mu.Lock()
+	cols, rows := s.termCols, s.termRows
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+	defer cancel()
+	return c.Resize(ctx, cols, rows)
+}
+
+// Signal delivers sig (SIGINT, SIGTERM or SIGKILL; the SIG prefix is
+// optional) to the running program.
+func (s *Session) Signal(sig string) error {
+	sig = strings.ToUpper(sig)
+	if !strings.HasPrefix(sig, "SIG") {
+		sig = "SIG" + sig
+	}
+	if !allowedSignals[sig] {
+		return fmt.Errorf("unsupported signal: %s", sig)
+	}
+
+	s.mu.Lock()
+	if s.State != StateRunning && s.State != StateWaitingInput {
+		defer s.mu.Unlock()
+		return fmt.Errorf("session not running (state=%s)", s.State)
+	}
+	c := s.controller
+	s.mu.Unlock()
+
+	if c == nil {
+		return fmt.Errorf("session has no process attached")
+	}
+	// outside mu, so a slow daemon doesn't hold up output or termination
+	ctx, cancel := context.WithTimeout(context.Background(), controlTimeout)
+	defer cancel()
+	return c.Signal(ctx, sig)
+}
@@ -0,0 +1,130 @@
+package session
+
+import (
+	"sync"
+	"time"
+)
+
+// subscriberBuffer is how many events a subscriber may lag behind before
+// publishers start blocking on it.
+const subscriberBuffer = 64
+
+type EventType string
+
+const (
+	EventStdout EventType = "stdout"
+	EventStderr EventType = "stderr"
+	EventState  EventType = "state"
+
+	// compiler output, emitted while the session is COMPILING
+	EventCompileStdout EventType = "compile_stdout"
+	EventCompileStderr EventType = "compile_stderr"
+)
+
+// Event is a chunk of output or a state change. Seq increases by one for
+// every event of a session, across stdout, stderr and state.
+type Event struct {
+	Type  EventType
+	Seq   uint64
+	Time  time.Time
+	Data  string
+	State State
+}
+
+// Subscription delivers session events as they happen. Publishers block
+// while its buffer is full, so a slow reader slows the program down
+// instead of growing memory.
+type Subscription struct {
+	C <-chan Event
+	// Ended is set when the session had already reached a terminal state
+	// on subscribing, so its final event is in the log and nothing but a
+	// later CLOSED will follow.
+	Ended bool
+
+	s         *Session
+	ch        chan Event
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// Subscribe returns the logged events with a seq greater than after, plus
+// a subscription for everything that happens afterwards. Pass 0 to get the
+// whole history.
+func (s *Session) Subscribe(after uint64) ([]Event, *Subscription) {
+	s.pubMu.Lock()
+	defer s.pubMu.Unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backlog := s.log.since(after)
+
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{
+		C:    ch,
+		s:    s,
+		ch:   ch,
+		done: make(chan struct{}),
+		// read under the same locks that publish state changes
+		Ended: s.State.IsTerminal(),
+	}
+	if s.subs == nil {
+		s.subs = make(map[*Subscription]struct{})
+	}
+	s.subs[sub] = struct{}{}
+
+	return backlog, sub
+}
+
+// Close detaches the subscription and unblocks any publisher waiting on it.
+func (sub *Subscription) Close() {
+	sub.closeOnce.Do(func() {
+		close(sub.done)
+
+		sub.s.mu.Lock()
+		delete(sub.s.subs, sub)
+		sub.s.mu.Unlock()
+	})
+}
+
+// must be called with s.mu held
+func (s *Session) subscribersLocked() []*Subscription {
+	if len(s.subs) == 0 {
+		return nil
+	}
+	subs := make([]*Subscription, 0, len(s.subs))
+	for sub := range s.subs {
+		subs = append(subs, sub)
+	}
+	return subs
+}
+
+// transition runs fn under s.mu and, if it reports a change, pushes the
+// new state to subscribers.
+func (s *Session) transition(fn func() bool) {
+	s.pubMu.Lock()
+	defer s.pubMu.Unlock()
+
+	s.mu.Lock()
+	changed := fn()
+	var ev Event
+	if changed {
+		ev = s.log.append(Event{Type: EventState, State: s.State})
+	}
+	subs := s.subscribersLocked()
+	s.mu.Unlock()
+
+	if changed {
+		broadcast(subs, ev)
+	}
+}
+
+// must be called with s.pubMu held
+func broadcast(subs []*Subscription, ev Event) {
+	for _, sub := range subs {
+		select {
+		case sub.ch <- ev:
+		case <-sub.done:
+		}
+	}
+}
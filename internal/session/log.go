@@ -0,0 +1,127 @@
+package session
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// eventLog is the ordered history of everything a session emitted.
+// Consecutive output chunks of the same stream share one entry so a
+// chatty program doesn't pay a full Event per write.
+type eventLog struct {
+	entries []logEntry
+	nextSeq uint64
+}
+
+// logEntry is a run of consecutive events of the same type. ends[i] and
+// times[i] belong to the chunk with seq first+i.
+type logEntry struct {
+	typ   EventType
+	state State
+	first uint64
+	data  []byte
+	ends  []int
+	times []int64
+}
+
+func (e *logEntry) last() uint64 {
+	return e.first + uint64(len(e.ends)) - 1
+}
+
+// append stamps ev with the next sequence number and the current time and
+// records it.
+func (l *eventLog) append(ev Event) Event {
+	l.nextSeq++
+	ev.Seq = l.nextSeq
+	ev.Time = time.Now()
+
+	n := len(l.entries)
+	if ev.Type != EventState && n > 0 && l.entries[n-1].typ == ev.Type {
+		e := &l.entries[n-1]
+		e.data = append(e.data, ev.Data...)
+		e.ends = append(e.ends, len(e.data))
+		e.times = append(e.times, ev.Time.UnixNano())
+		return ev
+	}
+
+	l.entries = append(l.entries, logEntry{
+		typ:   ev.Type,
+		state: ev.State,
+		first: ev.Seq,
+		data:  []byte(ev.Data),
+		ends:  []int{len(ev.Data)},
+		times: []int64{ev.Time.UnixNano()},
+	})
+	return ev
+}
+
+// since returns every event with a seq greater than after. Adjacent chunks
+// of the same stream come back merged, carrying the seq of the last one.
+func (l *eventLog) since(after uint64) []Event {
+	i := sort.Search(len(l.entries), func(i int) bool {
+		return l.entries[i].last() > after
+	})
+
+	var out []Event
+	for ; i < len(l.entries); i++ {
+		e := &l.entries[i]
+
+		skip := 0
+		if after >= e.first {
+			skip = int(after - e.first + 1)
+		}
+		start := 0
+		if skip > 0 {
+			start = e.ends[skip-1]
+		}
+
+		out = append(out, Event{
+			Type:  e.typ,
+			Seq:   e.last(),
+			Time:  time.Unix(0, e.times[skip]),
+			Data:  string(e.data[start:]),
+			State: e.state,
+		})
+	}
+	return out
+}
+
+func (t EventType) isProgramOutput() bool {
+	return t == EventStdout || t == EventStderr
+}
+
+// chunks returns every program output chunk in the order it was written, each
+// tagged with its stream and time.
+func (l *eventLog) chunks() []Event {
+	var out []Event
+	for i := range l.entries {
+		e := &l.entries[i]
+		if !e.typ.isProgramOutput() {
+			continue
+		}
+
+		start := 0
+		for j, end := range e.ends {
+			out = append(out, Event{
+				Type: e.typ,
+				Seq:  e.first + uint64(j),
+				Time: time.Unix(0, e.times[j]),
+				Data: string(e.data[start:end]),
+			})
+			start = end
+		}
+	}
+	return out
+}
+
+// combined is the program's stdout and stderr interleaved in write order.
+func (l *eventLog) combined() string {
+	var b strings.Builder
+	for i := range l.entries {
+		if l.entries[i].typ.isProgramOutput() {
+			b.Write(l.entries[i].data)
+		}
+	}
+	return b.String()
+}
@@ -1,15 +1,51 @@
 package session
 
-import "sync"
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Retention controls how long finished sessions stay queryable.
+type Retention struct {
+	// TTL is how long a finished session is kept after it ends.
+	TTL time.Duration
+	// MaxSessions caps how many finished sessions are kept at once.
+	MaxSessions int
+	// MaxBytes caps the output held by finished sessions combined.
+	MaxBytes int64
+	// RemoveOnFetch drops a finished session as soon as a client has
+	// received its final result, even if TTL hasn't passed.
+	RemoveOnFetch bool
+}
+
+var DefaultRetention = Retention{
+	TTL:         5 * time.Minute,
+	MaxSessions: 1000,
+	MaxBytes:    256 << 20, // 256 MB
+}
+
+const reapInterval = 10 * time.Second
 
 type Manager struct {
-	mu       sync.RWMutex
-	sessions map[string]*Session
+	mu        sync.RWMutex
+	sessions  map[string]*Session
+	retention Retention
+
+	stopReaper chan struct{}
+	stopOnce   sync.Once
 }
 
 func NewManager() *Manager {
+	return NewManagerWithRetention(DefaultRetention)
+}
+
+func NewManagerWithRetention(r Retention) *Manager {
 	return &Manager{
-		sessions: make(map[string]*Session),
+		sessions:   make(map[string]*Session),
+		retention:  r,
+		stopReaper: make(chan struct{}),
 	}
 }
 
@@ -31,3 +67,103 @@ func (m *Manager) Remove(id string) {
 	defer m.mu.Unlock()
 	delete(m.sessions, id)
 }
+
+// List returns every tracked session, oldest first.
+func (m *Manager) List() []*Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	list := make([]*Session, 0, len(m.sessions))
+	for _, s := range m.sessions {
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// MarkFetched records that a client received the final result of a
+// session, dropping it right away when RemoveOnFetch is set.
+func (m *Manager) MarkFetched(id string) {
+	if !m.retention.RemoveOnFetch {
+		return
+	}
+
+	s, ok := m.Get(id)
+	if !ok {
+		return
+	}
+
+	// the result can be delivered slightly before cleanup finishes
+	go func() {
+		<-s.CleanupDone()
+		log.Printf("Manager: session %s fetched, removing", id)
+		m.Remove(id)
+	}()
+}
+
+//
+// ---------------- Reaper ----------------
+//
+
+// StartReaper periodically evicts finished sessions past their retention.
+func (m *Manager) StartReaper() {
+	go func() {
+		ticker := time.NewTicker(reapInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				m.Reap(time.Now())
+			case <-m.stopReaper:
+				return
+			}
+		}
+	}()
+}
+
+func (m *Manager) StopReaper() {
+	m.stopOnce.Do(func() {
+		close(m.stopReaper)
+	})
+}
+
+// Reap removes finished sessions older than the TTL, then the oldest
+// remaining ones until the count and byte caps are met. Sessions still
+// running or being cleaned up are never touched.
+func (m *Manager) Reap(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var finished []*Session
+	for id, s := range m.sessions {
+		if !s.IsCleanedUp() {
+			continue
+		}
+		if m.retention.TTL > 0 && now.Sub(s.Snapshot().FinishedAt) > m.retention.TTL {
+			delete(m.sessions, id)
+			continue
+		}
+		finished = append(finished, s)
+	}
+
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].Snapshot().FinishedAt.Before(finished[j].Snapshot().FinishedAt)
+	})
+
+	var total int64
+	for _, s := range finished {
+		total += s.OutputSize()
+	}
+
+	for len(finished) > 0 &&
+		((m.retention.MaxSessions > 0 && len(finished) > m.retention.MaxSessions) ||
+			(m.retention.MaxBytes > 0 && total > m.retention.MaxBytes)) {
+		oldest := finished[0]
+		finished = finished[1:]
+		total -= oldest.OutputSize()
+		delete(m.sessions, oldest.ID)
+	}
+}
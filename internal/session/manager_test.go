@@ -0,0 +1,114 @@
+package session
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"execution-engine/internal/project"
+)
+
+// endedSession adds a session to m that finished at the given time with
+// output bytes of stdout, cleaned up or not.
+func endedSession(t *testing.T, m *Manager, id string, finishedAt time.Time, output int, cleaned bool) *Session {
+	t.Helper()
+
+	s := NewPending(id, "python", project.Project{})
+	s.MarkRunning()
+	s.StdoutWriter().Write([]byte(strings.Repeat("x", output)))
+	s.MarkFinished(ExitStatus{})
+	s.StopIdleWatcher()
+
+	s.mu.Lock()
+	s.FinishedAt = finishedAt
+	s.mu.Unlock()
+	if cleaned {
+		s.SignalCleanup()
+	}
+
+	m.Add(s)
+	return s
+}
+
+func sessionIDs(m *Manager) string {
+	var ids []string
+	for _, s := range m.List() {
+		ids = append(ids, s.ID)
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestReap(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name      string
+		retention Retention
+		want      string
+	}{
+		{"ttl", Retention{TTL: 5 * time.Minute}, "new mid"},
+		{"max sessions", Retention{MaxSessions: 1}, "new"},
+		// output counts twice, so each session holds 200 bytes
+		{"max bytes", Retention{MaxBytes: 450}, "new mid"},
+		{"within limits", Retention{TTL: time.Hour, MaxSessions: 3, MaxBytes: 600}, "new mid old"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManagerWithRetention(tt.retention)
+			endedSession(t, m, "new", now.Add(-time.Minute), 100, true)
+			endedSession(t, m, "mid", now.Add(-2*time.Minute), 100, true)
+			endedSession(t, m, "old", now.Add(-10*time.Minute), 100, true)
+
+			m.Reap(now)
+
+			// listed in creation order, which is not the order they finished in
+			if got := sessionIDs(m); got != tt.want {
+				t.Errorf("kept %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReapKeepsLiveSessions(t *testing.T) {
+	now := time.Now()
+	m := NewManagerWithRetention(Retention{TTL: time.Nanosecond, MaxSessions: 1, MaxBytes: 1})
+
+	running := NewPending("running", "python", project.Project{})
+	defer running.Stop()
+	running.MarkRunning()
+	running.StdoutWriter().Write([]byte("still going\n"))
+	m.Add(running)
+
+	// ended but its container isn't gone yet
+	endedSession(t, m, "cleaning", now.Add(-time.Hour), 100, false)
+	endedSession(t, m, "done", now.Add(-time.Hour), 100, true)
+
+	m.Reap(now)
+
+	if got := sessionIDs(m); got != "running cleaning" {
+		t.Errorf("kept %q, want %q", got, "running cleaning")
+	}
+}
+
+func TestRemoveOnFetch(t *testing.T) {
+	m := NewManagerWithRetention(Retention{RemoveOnFetch: true})
+	s := endedSession(t, m, "fetched", time.Now(), 0, false)
+
+	m.MarkFetched(s.ID)
+	time.Sleep(20 * time.Millisecond)
+	if _, ok := m.Get(s.ID); !ok {
+		t.Fatal("removed before cleanup finished")
+	}
+
+	s.SignalCleanup()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := m.Get(s.ID); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("not removed after cleanup")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
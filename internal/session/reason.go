@@ -0,0 +1,33 @@
+package session
+
+// TerminationReason explains why a session stopped.
+type TerminationReason string
+
+const (
+	ReasonExited          TerminationReason = "exited"
+	ReasonCompileError    TerminationReason = "compile_error"
+	ReasonOOM             TerminationReason = "oom"
+	ReasonTimeLimit       TerminationReason = "time_limit"
+	ReasonIdleTimeout     TerminationReason = "idle_timeout"
+	ReasonOutputLimit     TerminationReason = "output_limit"
+	ReasonClientAbandoned TerminationReason = "client_abandoned"
+	ReasonCancelled       TerminationReason = "cancelled"
+	ReasonStartFailed     TerminationReason = "start_failed"
+)
+
+// ExitStatus is what the runtime observed when the program exited on its own.
+type ExitStatus struct {
+	ExitCode      int
+	OOMKilled     bool
+	CompileFailed bool
+}
+
+func (st ExitStatus) reason() TerminationReason {
+	switch {
+	case st.OOMKilled:
+		return ReasonOOM
+	case st.CompileFailed:
+		return ReasonCompileError
+	}
+	return ReasonExited
+}
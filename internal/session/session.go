@@ -8,34 +8,76 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"execution-engine/internal/artifact"
+	"execution-engine/internal/project"
 )
 
 const (
 	MaxOutputBytes = 1 << 20 // 1 MB
+
+	// DefaultIdleTimeout is how long a running session may go without
+	// output or input before it is killed.
+	DefaultIdleTimeout = 30 * time.Second
+
+	eot = 0x04 // ^D, the terminal's end of input
 )
 
 type Session struct {
-	ID        string
-	State     State
-	StartedAt time.Time
+	ID         string
+	State      State
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	// set once the session reaches a terminal state
+	ExitCode  int
+	OOMKilled bool
+	Reason    TerminationReason
 
 	Language string
-	Code     string
+	Project  project.Project
+	// Tty runs the program on a pseudo-terminal; output is a single raw stream.
+	Tty bool
+	// Tenant the session runs for, if any.
+	Tenant string
+	// ArtifactPatterns select the workspace files kept after the program
+	// exits.
+	ArtifactPatterns []string
 
 	ContainerID string
 
-	Stdin  io.WriteCloser
-	Output io.Reader
+	Stdin io.WriteCloser
+	// stdinMu keeps writes to Stdin in order. They block while the program
+	// doesn't read, so they never happen under mu.
+	stdinMu sync.Mutex
+
+	controller Controller
+	termCols   uint
+	termRows   uint
+	resizeMu   sync.Mutex // orders Controller.Resize calls
 
 	Stdout strings.Builder
 	Stderr strings.Builder
 
+	compileStdout strings.Builder
+	compileStderr strings.Builder
+
+	artifacts        []artifact.Artifact
+	skippedArtifacts []artifact.Skipped
+
 	ctx    context.Context
 	cancel context.CancelFunc
 
 	done     chan struct{}
 	doneOnce sync.Once
 	mu       sync.Mutex
+
+	// pubMu serializes publishing so subscribers see events in order
+	pubMu sync.Mutex
+	subs  map[*Subscription]struct{}
+	log   eventLog
+
 	activeWS int
 	timer    *time.Timer
 
@@ -43,53 +85,80 @@ type Session struct {
 	idleTimeout  time.Duration
 	idleTimer    *time.Timer
 
+	timeLimit     time.Duration
+	deadlineTimer *time.Timer
+
 	cleanup     chan struct{}
 	cleanupOnce sync.Once
 }
 
-func New(
-	id string,
-	containerID string,
-	stdin io.WriteCloser,
-	output io.Reader,
-	ctx context.Context,
-	cancel context.CancelFunc,
-) *Session {
-	s := &Session{
-		ID:           id,
-		ContainerID:  containerID,
-		State:        StateRunning,
-		StartedAt:    time.Now(),
-		Stdin:        stdin,
-		Output:       output,
-		ctx:          ctx,
-		cancel:       cancel,
-		done:         make(chan struct{}),
-		idleTimeout:  30 * time.Second,
-		lastActivity: time.Now(),
-		cleanup:      make(chan struct{}),
+// Snapshot is a consistent point-in-time copy of a session's metadata.
+type Snapshot struct {
+	ID         string
+	Language   string
+	State      State
+	Tty        bool
+	Tenant     string
+	TimeLimit  time.Duration
+	CreatedAt  time.Time
+	StartedAt  time.Time
+	FinishedAt time.Time
+	ExitCode   int
+	OOMKilled  bool
+	Reason     TerminationReason
+	ActiveWS   int
+}
+
+func (s *Session) Snapshot() Snapshot {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return Snapshot{
+		ID:         s.ID,
+		Language:   s.Language,
+		State:      s.State,
+		Tty:        s.Tty,
+		Tenant:     s.Tenant,
+		TimeLimit:  s.timeLimit,
+		CreatedAt:  s.CreatedAt,
+		StartedAt:  s.StartedAt,
+		FinishedAt: s.FinishedAt,
+		ExitCode:   s.ExitCode,
+		OOMKilled:  s.OOMKilled,
+		Reason:     s.Reason,
+		ActiveWS:   s.activeWS,
 	}
-	s.startIdleWatcher()
-	return s
 }
 
-//
-// ---------------- Output handling (SAFE) ----------------
-//
+// ExitCodeKilled is the exit code reported for a session whose program
+// didn't exit on its own.
+const ExitCodeKilled = -1
 
-func (s *Session) AppendOutput(data []byte) {
-	s.mu.Lock()
-	s.Stdout.Write(data)
+// ReportedExitCode is the program's exit code if the session FINISHED,
+// and ExitCodeKilled if it was killed or never ran.
+func (s Snapshot) ReportedExitCode() int {
+	if s.State != StateFinished {
+		return ExitCodeKilled
+	}
+	return s.ExitCode
+}
 
-	overflow := s.Stdout.Len() > MaxOutputBytes
-	s.lastActivity = time.Now()
-	s.idleTimer.Reset(s.idleTimeout)
-	s.mu.Unlock()
+func (s *Session) GetState() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.State
+}
 
-	if overflow {
-		log.Printf("Session %s: output limit exceeded", s.ID)
-		s.Stop()
+// OutputSize is roughly how many bytes of output the session holds.
+func (s *Session) OutputSize() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// the event log keeps a second copy of every chunk
+	size := 2 * int64(s.Stdout.Len()+s.Stderr.Len()+s.compileStdout.Len()+s.compileStderr.Len())
+	for _, a := range s.artifacts {
+		size += a.Size
 	}
+	return size
 }
 
 func (s *Session) GetStdout() string {
@@ -104,45 +173,113 @@ func (s *Session) GetStderr() string {
 	return s.Stderr.String()
 }
 
+// SetArtifacts records the files collected from the workspace.
+func (s *Session) SetArtifacts(kept []artifact.Artifact, skipped []artifact.Skipped) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.artifacts = kept
+	s.skippedArtifacts = skipped
+}
+
+// Artifacts returns the collected files and the matching ones that were
+// not kept. Both are empty until the session has finished.
+func (s *Session) Artifacts() ([]artifact.Artifact, []artifact.Skipped) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.artifacts, s.skippedArtifacts
+}
+
+// Artifact returns the collected file at path.
+func (s *Session) Artifact(path string) (artifact.Artifact, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range s.artifacts {
+		if a.Path == path {
+			return a, true
+		}
+	}
+	return artifact.Artifact{}, false
+}
+
+// GetCompileOutput returns what the compiler wrote, empty for interpreted
+// languages.
+func (s *Session) GetCompileOutput() (stdout, stderr string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.compileStdout.String(), s.compileStderr.String()
+}
+
+// Transcript returns every stdout and stderr chunk in the order the
+// program wrote them. Compiler output is not included.
+func (s *Session) Transcript() []Event {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.chunks()
+}
+
+// GetCombinedOutput returns stdout and stderr interleaved as written.
+func (s *Session) GetCombinedOutput() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.log.combined()
+}
+
 func (s *Session) StdoutWriter() io.Writer {
-	return &safeWriter{s: s, isStderr: false}
+	return &safeWriter{s: s, stream: EventStdout}
 }
 
 func (s *Session) StderrWriter() io.Writer {
-	return &safeWriter{s: s, isStderr: true}
+	return &safeWriter{s: s, stream: EventStderr}
+}
+
+// CompileStdoutWriter and CompileStderrWriter receive the compiler's output.
+func (s *Session) CompileStdoutWriter() io.Writer {
+	return &safeWriter{s: s, stream: EventCompileStdout}
+}
+
+func (s *Session) CompileStderrWriter() io.Writer {
+	return &safeWriter{s: s, stream: EventCompileStderr}
 }
 
 type safeWriter struct {
-	s        *Session
-	isStderr bool
+	s      *Session
+	stream EventType
+}
+
+// must be called with s.mu held
+func (s *Session) bufferLocked(stream EventType) *strings.Builder {
+	switch stream {
+	case EventStderr:
+		return &s.Stderr
+	case EventCompileStdout:
+		return &s.compileStdout
+	case EventCompileStderr:
+		return &s.compileStderr
+	}
+	return &s.Stdout
 }
 
 func (w *safeWriter) Write(p []byte) (n int, err error) {
+	w.s.pubMu.Lock()
+	defer w.s.pubMu.Unlock()
+
 	w.s.mu.Lock()
-	defer w.s.mu.Unlock()
-
-	if w.isStderr {
-		n, err = w.s.Stderr.Write(p)
-	} else {
-		n, err = w.s.Stdout.Write(p)
-		w.s.lastActivity = time.Now()
-		if w.s.idleTimer != nil {
-			w.s.idleTimer.Reset(w.s.idleTimeout)
-		}
-	}
+	buf := w.s.bufferLocked(w.stream)
+	n, err = buf.Write(p)
+	w.s.touchLocked()
+	ev := w.s.log.append(Event{Type: w.stream, Data: string(p)})
 
-	overflow := false
-	if !w.isStderr && w.s.Stdout.Len() > MaxOutputBytes {
-		overflow = true
-	}
-	if w.isStderr && w.s.Stderr.Len() > MaxOutputBytes {
-		overflow = true
-	}
+	overflow := buf.Len() > MaxOutputBytes
+	subs := w.s.subscribersLocked()
+	w.s.mu.Unlock()
+
+	// blocks until every subscriber has room, throttling the producer
+	broadcast(subs, ev)
 
 	if overflow {
 		go func() {
 			log.Printf("Session %s: output limit exceeded", w.s.ID)
-			w.s.Stop()
+			w.s.terminate(StateTerminated, ReasonOutputLimit)
 		}()
 	}
 	return
@@ -153,76 +290,124 @@ func (w *safeWriter) Write(p []byte) (n int, err error) {
 //
 
 func (s *Session) WriteInput(data string) error {
+	stdin, err := s.acceptInput()
+	if err != nil {
+		return err
+	}
+
+	s.stdinMu.Lock()
+	defer s.stdinMu.Unlock()
+	_, err = stdin.Write([]byte(data))
+	return err
+}
+
+// CloseInput closes the write side of stdin so the program sees EOF.
+// Output keeps streaming until the program exits. A terminal can't be
+// closed without hanging it up, so TTY sessions get ^D instead.
+func (s *Session) CloseInput() error {
+	stdin, err := s.acceptInput()
+	if err != nil {
+		return err
+	}
+
+	s.stdinMu.Lock()
+	defer s.stdinMu.Unlock()
+	if s.Tty {
+		_, err := stdin.Write([]byte{eot})
+		return err
+	}
+
+	if cw, ok := stdin.(interface{ CloseWrite() error }); ok {
+		return cw.CloseWrite()
+	}
+	return stdin.Close()
+}
+
+// acceptInput checks the session takes input, records the activity and
+// returns its stdin. Killing the program unblocks a pending write.
+func (s *Session) acceptInput() (io.WriteCloser, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if s.State != StateRunning && s.State != StateWaitingInput {
-		return fmt.Errorf("session not accepting input (state=%s)", s.State)
+		return nil, fmt.Errorf("session not accepting input (state=%s)", s.State)
 	}
-
-	s.lastActivity = time.Now()
-	if s.idleTimer != nil {
-		s.idleTimer.Reset(s.idleTimeout)
+	if s.Stdin == nil {
+		return nil, fmt.Errorf("session has no stdin attached")
 	}
 
-	_, err := s.Stdin.Write([]byte(data))
-	return err
+	s.touchLocked()
+	return s.Stdin, nil
 }
 
 //
 // ---------------- Lifecycle handling ----------------
 //
 
-func (s *Session) MarkFinished() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if s.State == StateFinished || s.State == StateClosed {
-		return
-	}
+func (s *Session) MarkFinished(status ExitStatus) {
+	s.transition(func() bool {
+		if s.State.IsTerminal() {
+			return false
+		}
 
-	s.State = StateFinished
-	s.signalDone()
+		s.State = StateFinished
+		s.ExitCode = status.ExitCode
+		s.OOMKilled = status.OOMKilled
+		s.Reason = status.reason()
+		s.FinishedAt = time.Now()
+		s.signalDone()
+		return true
+	})
 }
 
-func (s *Session) MarkTerminated() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if s.State == StateFinished || s.State == StateClosed {
-		return
-	}
+func (s *Session) MarkTerminated(reason TerminationReason) {
+	s.transition(func() bool {
+		if s.State.IsTerminal() {
+			return false
+		}
 
-	s.State = StateTerminated
-	s.signalDone()
+		s.State = StateTerminated
+		s.Reason = reason
+		s.FinishedAt = time.Now()
+		s.signalDone()
+		return true
+	})
 }
 
 func (s *Session) Close() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	if s.State == StateClosed {
-		return
-	}
+	s.transition(func() bool {
+		if s.State == StateClosed {
+			return false
+		}
 
-	s.State = StateClosed
-	s.signalDone()
+		s.State = StateClosed
+		s.signalDone()
+		return true
+	})
 }
 
 func (s *Session) Stop() {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.terminate(StateTerminated, ReasonCancelled)
+}
 
-	if s.State == StateFinished || s.State == StateTerminated {
-		return
-	}
+// terminate moves the session into the given terminal state and cancels
+// the runtime so the container gets killed.
+func (s *Session) terminate(state State, reason TerminationReason) {
+	s.transition(func() bool {
+		if s.State.IsTerminal() {
+			return false
+		}
 
-	log.Printf("Session %s: Stopping session.", s.ID)
-	s.State = StateTerminated
-	if s.cancel != nil {
-		s.cancel()
-	}
-	s.signalDone()
+		log.Printf("Session %s: Stopping session (%s).", s.ID, reason)
+		s.State = state
+		s.Reason = reason
+		s.FinishedAt = time.Now()
+		if s.cancel != nil {
+			s.cancel()
+		}
+		s.signalDone()
+		return true
+	})
 }
 
 func (s *Session) Context() context.Context {
@@ -257,7 +442,7 @@ func (s *Session) DetachWS() bool {
 		log.Printf("Session %s: Last WebSocket detached, starting 1-minute termination timer.", s.ID)
 		s.timer = time.AfterFunc(1*time.Minute, func() {
 			log.Printf("Session %s: Termination timer fired.", s.ID)
-			s.Stop()
+			s.terminate(StateTerminated, ReasonClientAbandoned)
 		})
 	}
 	return s.activeWS == 0
@@ -273,13 +458,37 @@ func (s *Session) ActiveWSCount() int {
 // ---------------- Idle timeout ----------------
 //
 
+// startIdleWatcher arms the idle timeout once the program runs; waiting
+// in the queue is bounded by the engine instead.
+// must be called with s.mu held
 func (s *Session) startIdleWatcher() {
+	if s.idleTimeout <= 0 || s.idleTimer != nil {
+		return
+	}
 	s.idleTimer = time.AfterFunc(s.idleTimeout, func() {
 		log.Printf("Session %s idle timeout", s.ID)
-		s.Stop()
+		s.terminate(StateTerminated, ReasonIdleTimeout)
 	})
 }
 
+// touchLocked records activity, pushing the idle timeout back.
+// must be called with s.mu held
+func (s *Session) touchLocked() {
+	s.lastActivity = time.Now()
+	if s.idleTimer != nil {
+		s.idleTimer.Reset(s.idleTimeout)
+	}
+}
+
+// SetIdleTimeout changes how long the program may go without output or
+// input. It takes effect when the session starts running; zero disables
+// it.
+func (s *Session) SetIdleTimeout(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.idleTimeout = d
+}
+
 func (s *Session) StopIdleWatcher() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -289,12 +498,42 @@ func (s *Session) StopIdleWatcher() {
 	}
 }
 
+//
+// ---------------- Execution time limit ----------------
+//
+
+// SetTimeLimit sets the wall-clock budget the program gets once it is
+// running. Zero means no limit.
+func (s *Session) SetTimeLimit(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.timeLimit = d
+}
+
+// must be called with s.mu held
+func (s *Session) startDeadline() {
+	if s.timeLimit <= 0 || s.deadlineTimer != nil {
+		return
+	}
+	s.deadlineTimer = time.AfterFunc(s.timeLimit, func() {
+		log.Printf("Session %s: time limit of %s exceeded", s.ID, s.timeLimit)
+		s.terminate(StateTimedOut, ReasonTimeLimit)
+	})
+}
+
 //
 // ---------------- Synchronization ----------------
 //
 
+// must be called with s.mu held
 func (s *Session) signalDone() {
 	s.doneOnce.Do(func() {
+		if s.deadlineTimer != nil {
+			s.deadlineTimer.Stop()
+		}
+		if s.idleTimer != nil {
+			s.idleTimer.Stop()
+		}
 		close(s.done)
 	})
 }
@@ -313,41 +552,85 @@ func (s *Session) CleanupDone() <-chan struct{} {
 	return s.cleanup
 }
 
-func NewPending(id, lang, code string) *Session {
+// IsCleanedUp reports whether the session ended and its resources are gone.
+func (s *Session) IsCleanedUp() bool {
+	select {
+	case <-s.cleanup:
+		return true
+	default:
+		return false
+	}
+}
+
+func NewPending(id, lang string, p project.Project) *Session {
+	// cancelled by Stop so a session can be killed while still queued
+	ctx, cancel := context.WithCancel(context.Background())
+
 	s := &Session{
 		ID:           id,
 		State:        StateWaiting,
-		Language:  lang,
-		Code:      code,
-		StartedAt: time.Now(),
-		done:      make(chan struct{}),
-		idleTimeout: 30 * time.Second,
+		Language:     lang,
+		Project:      p,
+		CreatedAt:    time.Now(),
+		ctx:          ctx,
+		cancel:       cancel,
+		done:         make(chan struct{}),
+		idleTimeout:  DefaultIdleTimeout,
 		lastActivity: time.Now(),
 		cleanup:      make(chan struct{}),
 	}
-	s.startIdleWatcher()
+	s.log.append(Event{Type: EventState, State: s.State})
 	return s
 }
 
+// MarkCompiling moves a waiting session into its compile phase. The
+// compile runs under its own limits, so neither the idle timeout nor the
+// time limit counts it.
+func (s *Session) MarkCompiling() {
+	s.transition(func() bool {
+		if s.State != StateWaiting {
+			return false
+		}
+		s.State = StateCompiling
+		return true
+	})
+}
+
 func (s *Session) MarkRunning() {
+	s.transition(func() bool {
+		if s.State.IsTerminal() {
+			return false
+		}
+		s.State = StateRunning
+		s.StartedAt = time.Now()
+		s.touchLocked()
+		s.startIdleWatcher()
+		s.startDeadline()
+		return true
+	})
+}
+
+// Duration reports how long the program ran, or has been running so far.
+func (s *Session) Duration() time.Duration {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.State = StateRunning
+
+	if s.StartedAt.IsZero() {
+		return 0
+	}
+	if s.FinishedAt.IsZero() {
+		return time.Since(s.StartedAt)
+	}
+	return s.FinishedAt.Sub(s.StartedAt)
 }
 
 func (s *Session) SetRuntime(
 	containerID string,
 	stdin io.WriteCloser,
-	output io.Reader,
-	ctx context.Context,
-	cancel context.CancelFunc,
 ) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	s.ContainerID = containerID
 	s.Stdin = stdin
-	s.Output = output
-	s.ctx = ctx
-	s.cancel = cancel
 }
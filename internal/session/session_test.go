@@ -0,0 +1,348 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"execution-engine/internal/project"
+)
+
+func TestLifecycle(t *testing.T) {
+	tests := []struct {
+		name       string
+		run        func(s *Session)
+		wantState  State
+		wantReason TerminationReason
+		wantCode   int
+	}{
+		{
+			name: "finished",
+			run: func(s *Session) {
+				s.MarkRunning()
+				s.MarkFinished(ExitStatus{ExitCode: 2})
+			},
+			wantState:  StateFinished,
+			wantReason: ReasonExited,
+			wantCode:   2,
+		},
+		{
+			name: "oom beats compile marker",
+			run: func(s *Session) {
+				s.MarkRunning()
+				s.MarkFinished(ExitStatus{ExitCode: 137, OOMKilled: true, CompileFailed: true})
+			},
+			wantState:  StateFinished,
+			wantReason: ReasonOOM,
+			wantCode:   137,
+		},
+		{
+			name: "compile error",
+			run: func(s *Session) {
+				s.MarkCompiling()
+				s.MarkFinished(ExitStatus{ExitCode: 1, CompileFailed: true})
+			},
+			wantState:  StateFinished,
+			wantReason: ReasonCompileError,
+			wantCode:   1,
+		},
+		{
+			name: "stop while waiting",
+			run: func(s *Session) {
+				s.Stop()
+			},
+			wantState:  StateTerminated,
+			wantReason: ReasonCancelled,
+		},
+		{
+			name: "stop wins over a late exit",
+			run: func(s *Session) {
+				s.MarkRunning()
+				s.Stop()
+				s.MarkFinished(ExitStatus{ExitCode: 137})
+			},
+			wantState:  StateTerminated,
+			wantReason: ReasonCancelled,
+		},
+		{
+			name: "no restart after stop",
+			run: func(s *Session) {
+				s.Stop()
+				s.MarkRunning()
+			},
+			wantState:  StateTerminated,
+			wantReason: ReasonCancelled,
+		},
+		{
+			name: "time limit",
+			run: func(s *Session) {
+				s.SetTimeLimit(10 * time.Millisecond)
+				s.MarkRunning()
+				<-s.Done()
+			},
+			wantState:  StateTimedOut,
+			wantReason: ReasonTimeLimit,
+		},
+		{
+			name: "idle timeout",
+			run: func(s *Session) {
+				s.SetIdleTimeout(10 * time.Millisecond)
+				s.MarkRunning()
+				<-s.Done()
+			},
+			wantState:  StateTerminated,
+			wantReason: ReasonIdleTimeout,
+		},
+		{
+			name: "idle timeout within the time limit",
+			run: func(s *Session) {
+				s.SetIdleTimeout(10 * time.Millisecond)
+				s.SetTimeLimit(time.Minute)
+				s.MarkRunning()
+				<-s.Done()
+			},
+			wantState:  StateTerminated,
+			wantReason: ReasonIdleTimeout,
+		},
+		{
+			name: "stderr counts as activity",
+			run: func(s *Session) {
+				// gaps far below the timeout, twice its length in total
+				s.SetIdleTimeout(150 * time.Millisecond)
+				s.MarkRunning()
+				for i := 0; i < 30; i++ {
+					s.StderrWriter().Write([]byte("."))
+					time.Sleep(10 * time.Millisecond)
+				}
+				s.MarkFinished(ExitStatus{})
+			},
+			wantState:  StateFinished,
+			wantReason: ReasonExited,
+		},
+		{
+			name: "output limit",
+			run: func(s *Session) {
+				s.MarkRunning()
+				s.StderrWriter().Write([]byte(strings.Repeat("x", MaxOutputBytes+1)))
+				<-s.Done()
+			},
+			wantState:  StateTerminated,
+			wantReason: ReasonOutputLimit,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewPending(NewID(), "python", project.Project{})
+			defer s.Stop()
+
+			tt.run(s)
+
+			snap := s.Snapshot()
+			if snap.State != tt.wantState || snap.Reason != tt.wantReason || snap.ExitCode != tt.wantCode {
+				t.Errorf("got state=%s reason=%s code=%d, want state=%s reason=%s code=%d",
+					snap.State, snap.Reason, snap.ExitCode,
+					tt.wantState, tt.wantReason, tt.wantCode)
+			}
+			if snap.State.IsTerminal() && snap.FinishedAt.IsZero() {
+				t.Error("FinishedAt not set")
+			}
+		})
+	}
+}
+
+func TestSubscribeResume(t *testing.T) {
+	s := NewPending(NewID(), "python", project.Project{})
+	defer s.Stop()
+
+	s.MarkRunning()
+	s.StdoutWriter().Write([]byte("a"))
+	s.StdoutWriter().Write([]byte("b"))
+	s.StderrWriter().Write([]byte("E"))
+
+	all, sub := s.Subscribe(0)
+	sub.Close()
+
+	// WAITING, RUNNING, "a", "b", "E"
+	if got := all[len(all)-1].Seq; got != 5 {
+		t.Fatalf("last seq = %d, want 5", got)
+	}
+
+	tests := []struct {
+		after uint64
+		want  []string
+	}{
+		{after: 2, want: []string{"stdout:ab", "stderr:E"}},
+		{after: 3, want: []string{"stdout:b", "stderr:E"}},
+		{after: 4, want: []string{"stderr:E"}},
+		{after: 5, want: nil},
+	}
+	for _, tt := range tests {
+		backlog, sub := s.Subscribe(tt.after)
+		sub.Close()
+
+		var got []string
+		for _, ev := range backlog {
+			got = append(got, string(ev.Type)+":"+ev.Data)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("after=%d: got %v, want %v", tt.after, got, tt.want)
+		}
+	}
+}
+
+func TestSubscribeLive(t *testing.T) {
+	s := NewPending(NewID(), "python", project.Project{})
+	defer s.Stop()
+
+	_, sub := s.Subscribe(0)
+	defer sub.Close()
+
+	s.MarkRunning()
+	s.StdoutWriter().Write([]byte("hi"))
+	s.MarkFinished(ExitStatus{})
+
+	var got []string
+	for len(got) < 3 {
+		select {
+		case ev := <-sub.C:
+			got = append(got, string(ev.Type)+":"+ev.Data+string(ev.State))
+		case <-time.After(time.Second):
+			t.Fatalf("timed out, got %v", got)
+		}
+	}
+
+	want := "state:RUNNING,stdout:hi,state:FINISHED"
+	if strings.Join(got, ",") != want {
+		t.Errorf("got %v, want %s", got, want)
+	}
+}
+
+func TestSubscribeEnded(t *testing.T) {
+	s := NewPending(NewID(), "python", project.Project{})
+	defer s.Stop()
+
+	s.MarkRunning()
+	_, sub := s.Subscribe(0)
+	defer sub.Close()
+	if sub.Ended {
+		t.Fatal("running session reported as ended")
+	}
+
+	// ending after subscribing must be delivered, not only logged
+	s.MarkFinished(ExitStatus{ExitCode: 3})
+	select {
+	case ev := <-sub.C:
+		if ev.State != StateFinished {
+			t.Errorf("got %+v, want FINISHED", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("final state not delivered")
+	}
+
+	backlog, late := s.Subscribe(0)
+	late.Close()
+	if !late.Ended || backlog[len(backlog)-1].State != StateFinished {
+		t.Errorf("late subscriber: ended=%v, backlog %+v", late.Ended, backlog)
+	}
+}
+
+func TestTranscriptKeepsOrder(t *testing.T) {
+	s := NewPending(NewID(), "python", project.Project{})
+	defer s.Stop()
+
+	s.MarkCompiling()
+	s.CompileStderrWriter().Write([]byte("warning: unused\n"))
+	s.MarkRunning()
+
+	s.StdoutWriter().Write([]byte("1\n"))
+	s.StderrWriter().Write([]byte("Traceback\n"))
+	s.StdoutWriter().Write([]byte("2\n"))
+
+	if got := s.GetCombinedOutput(); got != "1\nTraceback\n2\n" {
+		t.Errorf("combined = %q", got)
+	}
+	if got := len(s.Transcript()); got != 3 {
+		t.Errorf("transcript has %d chunks, want 3", got)
+	}
+	if _, got := s.GetCompileOutput(); got != "warning: unused\n" {
+		t.Errorf("compile stderr = %q", got)
+	}
+}
+
+type recordingStdin struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (r *recordingStdin) Close() error {
+	r.closed = true
+	return nil
+}
+
+func TestCloseInputTTY(t *testing.T) {
+	s := NewPending(NewID(), "python", project.Project{})
+	defer s.Stop()
+	s.Tty = true
+
+	stdin := &recordingStdin{}
+	s.SetRuntime("c1", stdin)
+	s.MarkRunning()
+
+	if err := s.CloseInput(); err != nil {
+		t.Fatal(err)
+	}
+	if stdin.closed || stdin.String() != "\x04" {
+		t.Errorf("closed=%v, wrote %q; want ^D written and stdin left open", stdin.closed, stdin.String())
+	}
+}
+
+// hungController stands in for an unresponsive container daemon.
+type hungController struct {
+	release chan struct{}
+}
+
+func (h hungController) Resize(ctx context.Context, cols, rows uint) error {
+	select {
+	case <-h.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h hungController) Signal(ctx context.Context, sig string) error {
+	select {
+	case <-h.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestControlDoesNotBlockSession(t *testing.T) {
+	s := NewPending(NewID(), "python", project.Project{})
+	s.Tty = true
+	hung := hungController{release: make(chan struct{})}
+	defer close(hung.release)
+	s.SetController(hung)
+	s.MarkRunning()
+
+	go s.Signal("SIGINT")
+	go s.Resize(80, 24)
+	time.Sleep(20 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.StdoutWriter().Write([]byte("still streaming\n"))
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("a hung Signal or Resize blocked output and Stop")
+	}
+}
@@ -4,11 +4,22 @@ type State string
 
 const (
 	StateWaiting      State = "WAITING"
+	StateCompiling    State = "COMPILING"
 	StateCreated      State = "CREATED"
 	StateStarting     State = "STARTING"
 	StateRunning      State = "RUNNING"
 	StateWaitingInput State = "WAITING_FOR_INPUT"
 	StateFinished     State = "FINISHED"
 	StateTerminated   State = "TERMINATED"
+	StateTimedOut     State = "TIMED_OUT"
 	StateClosed       State = "CLOSED"
 )
+
+// IsTerminal reports whether no further transitions can happen from st.
+func (st State) IsTerminal() bool {
+	switch st {
+	case StateFinished, StateTerminated, StateTimedOut, StateClosed:
+		return true
+	}
+	return false
+}